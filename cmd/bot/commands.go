@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/service"
+	"github.com/dntjd1097/allora-checker-bot/internal/utils"
+)
+
+// runCommand runs a maintenance subcommand instead of the bot
+func runCommand(cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "export":
+		return runExport(cfg, args)
+	case "backfill":
+		return runBackfill(cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q, available commands: export, backfill", name)
+	}
+}
+
+// runExport writes stored history as CSV or JSON to a file or stdout.
+// The store is opened read-only, so file history can be exported while the bot runs;
+// a bolt database is locked by the running bot, use /export in Telegram instead.
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	address := flags.String("address", "all", "address or alias to export, or all for every stored address")
+	since := flags.String("since", "", "time window such as 24h or 7d, or a date such as 2026-10-01")
+	format := flags.String("format", service.ExportCSV, "export format, csv or json")
+	out := flags.String("out", "", "output file, stdout when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != service.ExportCSV && *format != service.ExportJSON {
+		return fmt.Errorf("unknown export format %q, use %s or %s", *format, service.ExportCSV, service.ExportJSON)
+	}
+
+	now := time.Now()
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = utils.ParseSince(*since, now); err != nil {
+			if from, err = utils.ParseSince("since "+*since, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	store, err := service.OpenReadOnlyHistoryStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	var addresses []string
+	if *address == "all" {
+		if addresses, err = store.Addresses(ctx); err != nil {
+			return err
+		}
+	} else {
+		resolved, ok := cfg.ResolveAddress(*address)
+		if !ok {
+			return fmt.Errorf("unknown address or alias %q", *address)
+		}
+		addresses = []string{resolved}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *out, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := service.NewHistoryService(store).Export(ctx, w, addresses, from, now, *format)
+	if err != nil {
+		return err
+	}
+	log.Printf("Exported %d snapshots of %d addresses", count, len(addresses))
+	return nil
+}
+
+// runBackfill stores history for past block heights read from historical chain state.
+// File history is locked per write, so it can be backfilled while the bot runs;
+// a bolt database is locked by the running bot, which must be stopped first.
+func runBackfill(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	address := flags.String("address", "all", "address or alias to backfill, or all for every tracked address")
+	to := flags.Int64("to", 0, "newest block height to backfill, the latest block when 0")
+	from := flags.Int64("from", 0, "oldest block height to backfill, 24 steps before -to when 0")
+	step := flags.Int64("step", 720, "blocks between backfilled heights")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *step <= 0 {
+		return fmt.Errorf("step must be positive")
+	}
+
+	addresses := cfg.Allora.Address
+	if *address != "all" {
+		resolved, ok := cfg.ResolveAddress(*address)
+		if !ok {
+			return fmt.Errorf("unknown address or alias %q", *address)
+		}
+		addresses = []string{resolved}
+	}
+
+	store, err := service.OpenHistoryStore(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Cancel the walk on SIGINT or SIGTERM, heights fetched so far are still stored
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	alloraClient, apiClient := newClients(cfg)
+	backfiller := service.NewBackfiller(service.NewAlloraService(apiClient, cfg.Allora.CacheTTL), alloraClient, service.NewHistoryService(store), cfg)
+
+	if *to == 0 {
+		if *to, err = backfiller.LatestHeight(ctx); err != nil {
+			return err
+		}
+	}
+	if *from == 0 {
+		*from = *to - 24*(*step)
+		if *from < 1 {
+			*from = 1
+		}
+	}
+	if *from <= 0 || *to <= 0 {
+		return fmt.Errorf("block heights must be positive, got -from %d -to %d", *from, *to)
+	}
+	if *from > *to {
+		return fmt.Errorf("-from %d is above -to %d", *from, *to)
+	}
+
+	for _, address := range addresses {
+		count, err := backfiller.Backfill(ctx, address, *from, *to, *step)
+		if err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", address, err)
+		}
+		log.Printf("Backfilled %d heights of %s between %d and %d", count, address, *from, *to)
+	}
+	return nil
+}
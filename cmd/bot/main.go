@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/config"
 	"github.com/dntjd1097/allora-checker-bot/internal/service"
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
 )
 
 func main() {
@@ -20,6 +24,14 @@ func main() {
 	}
 	log.Println("Configuration loaded successfully")
 
+	// Run a maintenance subcommand such as export instead of the bot
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("Error running %s: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// Initialize Telegram bot with retry
 	log.Println("Initializing Telegram bot...")
 	bot, err := service.InitBot(cfg.Telegram.Token, 3)
@@ -30,29 +42,64 @@ func main() {
 
 	// Initialize services
 	log.Println("Initializing services...")
-	// alloraService := service.NewAlloraService(cfg.Allora.API)
-	// historyService := service.NewHistoryService("history")
+	alloraClient, apiClient := newClients(cfg)
+	alloraService := service.NewAlloraService(apiClient, cfg.Allora.CacheTTL)
+	historyStore, err := service.OpenHistoryStore(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Error opening history store: %v", err)
+	}
+	historyService := service.NewHistoryService(historyStore)
+	defer historyService.Close()
 	log.Println("Services initialized successfully")
 
-	// Create update config with bot and config
-	updateConfig := service.NewUpdateConfig(bot, cfg)
+	// Create telegram service
+	telegramService := service.NewTelegramService(bot, cfg, alloraService, historyService)
+	log.Println("Telegram service created successfully")
 
-	// Create ticker for periodic checks
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+	// Create scheduler for periodic checks
+	scheduler, err := service.NewScheduler("periodic rank check", cfg.Checker.Interval, cfg.Checker.Cron, telegramService.CheckRankChanges)
+	if err != nil {
+		log.Fatalf("Error creating scheduler: %v", err)
+	}
 
-	// Create telegram service
-	// telegramService := service.NewTelegramService(bot, cfg, alloraService, historyService)
-	// log.Println("Telegram service created successfully")
+	// Cancel everything in flight on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Start handling updates
 	log.Println("Starting to handle updates...")
-	go service.HandleUpdates(updateConfig)
+	go telegramService.HandleUpdates(ctx)
 
 	log.Println("Bot is now running. Press Ctrl+C to stop.")
 	// Handle periodic rank checks
-	for range ticker.C {
-		log.Println("Running periodic rank check...")
-		// telegramService.CheckRankChanges()
+	if cfg.Checker.Mode == "blocks" {
+		subscriber, err := client.NewBlockSubscriber(cfg.Allora.RPC)
+		if err != nil {
+			log.Fatalf("Error creating block subscriber: %v", err)
+		}
+		service.NewBlockTrigger(subscriber, alloraClient, scheduler, cfg.Checker.EveryBlocks, cfg.Checker.EpochTopics).Run(ctx)
+	} else {
+		scheduler.Run(ctx)
+	}
+	log.Println("Shutting down Allora Checker Bot...")
+}
+
+// newClients creates the REST client and the client used for queries,
+// which falls back to RPC when the REST gateway is down and an RPC endpoint is configured
+func newClients(cfg *config.Config) (*client.AlloraClient, client.Client) {
+	retryPolicy := client.RetryPolicy{
+		MaxAttempts: cfg.Allora.Retry.Attempts,
+		BaseDelay:   cfg.Allora.Retry.BaseDelay,
+		MaxDelay:    cfg.Allora.Retry.MaxDelay,
+		Deadline:    cfg.Allora.Retry.Deadline,
+	}
+	alloraClient := client.NewAlloraClient(cfg.Allora.Forge, cfg.Allora.API, retryPolicy, cfg.Allora.EmissionsVersions)
+	if _, err := alloraClient.DetectVersion(context.Background()); err != nil {
+		log.Printf("Error detecting emissions version: %v", err)
+	}
+
+	if cfg.Allora.RPC == "" {
+		return alloraClient, alloraClient
 	}
+	return alloraClient, client.NewFallbackClient(alloraClient, client.NewRPCClient(cfg.Allora.RPC, retryPolicy, cfg.Allora.EmissionsVersions))
 }
@@ -0,0 +1,297 @@
+// Package chart renders time series as PNG line charts.
+// Rendering uses only the built-in bitmap font and no system resources,
+// so the same input always produces the same image.
+package chart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Point is a value observed at a time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a named line of points in time order
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Panel is one plot area of a chart sharing a y axis
+type Panel struct {
+	Title  string
+	Series []Series
+	// Invert draws smaller values on top, as for rankings where 1 is best
+	Invert bool
+	// Integer labels the y axis with whole numbers
+	Integer bool
+}
+
+// Chart is a stack of panels sharing a time axis
+type Chart struct {
+	Title  string
+	Panels []Panel
+	Width  int
+	// PanelHeight is the height of every panel including its title and axis labels
+	PanelHeight int
+}
+
+// Layout of every panel in pixels
+const (
+	titleHeight  = 28
+	panelTop     = 22
+	panelBottom  = 24
+	marginLeft   = 64
+	marginRight  = 16
+	lineWidth    = 2
+	yTicks       = 5
+	xTicks       = 4
+	timeLayout   = "01-02 15:04"
+	legendSwatch = 12
+)
+
+var (
+	colorBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	colorAxis       = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	colorGrid       = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+
+	// palette is cycled through for the series of a panel
+	palette = []color.RGBA{
+		{0x1f, 0x77, 0xb4, 0xff},
+		{0xff, 0x7f, 0x0e, 0xff},
+		{0x2c, 0xa0, 0x2c, 0xff},
+		{0xd6, 0x27, 0x28, 0xff},
+		{0x94, 0x67, 0xbd, 0xff},
+		{0x8c, 0x56, 0x4b, 0xff},
+		{0xe3, 0x77, 0xc2, 0xff},
+		{0x17, 0xbe, 0xcf, 0xff},
+	}
+)
+
+// Render draws the chart and encodes it as PNG
+func (c *Chart) Render(w io.Writer) error {
+	width, panelHeight := c.Width, c.PanelHeight
+	if width <= 0 {
+		width = 900
+	}
+	if panelHeight <= 0 {
+		panelHeight = 240
+	}
+
+	start, end, ok := c.timeRange()
+	if !ok {
+		return fmt.Errorf("no points to chart")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, titleHeight+panelHeight*len(c.Panels)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(colorBackground), image.Point{}, draw.Src)
+	drawText(img, c.Title, width/2-textWidth(c.Title)/2, 19, colorText)
+
+	for i, panel := range c.Panels {
+		top := titleHeight + i*panelHeight
+		area := image.Rect(marginLeft, top+panelTop, width-marginRight, top+panelHeight-panelBottom)
+		drawPanel(img, panel, area, start, end)
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return nil
+}
+
+// timeRange returns the earliest and latest time of every point
+func (c *Chart) timeRange() (time.Time, time.Time, bool) {
+	var start, end time.Time
+	found := false
+	for _, panel := range c.Panels {
+		for _, series := range panel.Series {
+			for _, p := range series.Points {
+				if !found || p.Time.Before(start) {
+					start = p.Time
+				}
+				if !found || p.Time.After(end) {
+					end = p.Time
+				}
+				found = true
+			}
+		}
+	}
+	return start, end, found
+}
+
+// drawPanel draws the grid, axis labels, legend and lines of a panel inside area
+func drawPanel(img *image.RGBA, panel Panel, area image.Rectangle, start, end time.Time) {
+	lo, hi := valueRange(panel.Series)
+	span := end.Sub(start)
+
+	x := func(t time.Time) float64 {
+		if span <= 0 {
+			return float64(area.Min.X+area.Max.X) / 2
+		}
+		return float64(area.Min.X) + float64(t.Sub(start))/float64(span)*float64(area.Dx())
+	}
+	y := func(v float64) float64 {
+		ratio := (v - lo) / (hi - lo)
+		if panel.Invert {
+			ratio = 1 - ratio
+		}
+		return float64(area.Max.Y) - ratio*float64(area.Dy())
+	}
+
+	// Title and legend above the plot area
+	drawText(img, panel.Title, area.Min.X, area.Min.Y-8, colorText)
+	legendX := area.Min.X + textWidth(panel.Title) + 16
+	for i, series := range panel.Series {
+		c := palette[i%len(palette)]
+		fillRect(img, image.Rect(legendX, area.Min.Y-17, legendX+legendSwatch, area.Min.Y-17+legendSwatch/2+2), c)
+		drawText(img, series.Name, legendX+legendSwatch+4, area.Min.Y-8, colorText)
+		legendX += legendSwatch + 4 + textWidth(series.Name) + 12
+	}
+
+	// Horizontal grid lines with value labels
+	for i := 0; i <= yTicks; i++ {
+		v := lo + (hi-lo)*float64(i)/yTicks
+		py := int(math.Round(y(v)))
+		fillRect(img, image.Rect(area.Min.X, py, area.Max.X, py+1), colorGrid)
+		label := formatValue(v, hi-lo)
+		if panel.Integer {
+			label = fmt.Sprintf("%.0f", v)
+		}
+		drawText(img, label, area.Min.X-6-textWidth(label), py+4, colorText)
+	}
+
+	// Time labels below the plot area
+	for i := 0; i <= xTicks; i++ {
+		t := start.Add(span * time.Duration(i) / xTicks)
+		px := int(math.Round(x(t)))
+		fillRect(img, image.Rect(px, area.Max.Y, px+1, area.Max.Y+4), colorAxis)
+		label := t.Format(timeLayout)
+		lx := px - textWidth(label)/2
+		if lx+textWidth(label) > img.Bounds().Max.X {
+			lx = img.Bounds().Max.X - textWidth(label)
+		}
+		drawText(img, label, lx, area.Max.Y+16, colorText)
+		if span <= 0 {
+			break
+		}
+	}
+
+	// Axes
+	fillRect(img, image.Rect(area.Min.X, area.Min.Y, area.Min.X+1, area.Max.Y+1), colorAxis)
+	fillRect(img, image.Rect(area.Min.X, area.Max.Y, area.Max.X, area.Max.Y+1), colorAxis)
+
+	for i, series := range panel.Series {
+		c := palette[i%len(palette)]
+		for j, p := range series.Points {
+			px, py := x(p.Time), y(p.Value)
+			if j == 0 {
+				drawDot(img, px, py, c)
+				continue
+			}
+			prev := series.Points[j-1]
+			drawLine(img, x(prev.Time), y(prev.Value), px, py, c)
+		}
+	}
+}
+
+// valueRange returns the padded range of every value in the series
+func valueRange(series []Series) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, p := range s.Points {
+			lo = math.Min(lo, p.Value)
+			hi = math.Max(hi, p.Value)
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return 0, 1
+	}
+	if hi == lo {
+		return lo - 1, hi + 1
+	}
+	pad := (hi - lo) * 0.05
+	return lo - pad, hi + pad
+}
+
+// formatValue formats an axis label with precision suited to the visible range
+func formatValue(v, span float64) string {
+	switch {
+	case span >= 50:
+		return fmt.Sprintf("%.0f", v)
+	case span >= 1:
+		return fmt.Sprintf("%.1f", v)
+	default:
+		return fmt.Sprintf("%.3g", v)
+	}
+}
+
+// drawLine draws an anti-aliased line segment as a filled quad
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, c color.RGBA) {
+	dx, dy := x1-x0, y1-y0
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		drawDot(img, x0, y0, c)
+		return
+	}
+	nx, ny := -dy/length*lineWidth/2, dx/length*lineWidth/2
+
+	// Rasterize only the bounding box of the segment
+	r := image.Rect(
+		int(math.Floor(math.Min(x0, x1)))-lineWidth, int(math.Floor(math.Min(y0, y1)))-lineWidth,
+		int(math.Ceil(math.Max(x0, x1)))+lineWidth, int(math.Ceil(math.Max(y0, y1)))+lineWidth,
+	).Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	ox, oy := float64(r.Min.X), float64(r.Min.Y)
+
+	z := vector.NewRasterizer(r.Dx(), r.Dy())
+	z.MoveTo(float32(x0+nx-ox), float32(y0+ny-oy))
+	z.LineTo(float32(x1+nx-ox), float32(y1+ny-oy))
+	z.LineTo(float32(x1-nx-ox), float32(y1-ny-oy))
+	z.LineTo(float32(x0-nx-ox), float32(y0-ny-oy))
+	z.ClosePath()
+	z.Draw(img, r, image.NewUniform(c), image.Point{})
+}
+
+// drawDot marks a single point
+func drawDot(img *image.RGBA, x, y float64, c color.RGBA) {
+	px, py := int(math.Round(x)), int(math.Round(y))
+	fillRect(img, image.Rect(px-2, py-2, px+2, py+2), c)
+}
+
+// fillRect fills a rectangle with a solid color
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// drawText draws text with its baseline at y
+func drawText(img *image.RGBA, text string, x, y int, c color.RGBA) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// textWidth returns the width of text in pixels
+func textWidth(text string) int {
+	return font.MeasureString(basicfont.Face7x13, text).Ceil()
+}
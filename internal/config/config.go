@@ -1,22 +1,75 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"time"
 
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
 	"gopkg.in/yaml.v2"
 )
 
+// History storage backends selectable in config.yaml
+const (
+	HistoryBackendFile = "file"
+	HistoryBackendBolt = "bolt"
+)
+
 type Config struct {
 	Telegram struct {
-		Token         string `yaml:"token"`
-		ChatID        string `yaml:"chat_id"`
-		MessageThread int    `yaml:"message_thread"`
+		Token          string        `yaml:"token"`
+		ChatID         string        `yaml:"chat_id"`
+		MessageThread  int           `yaml:"message_thread"`
+		CommandTimeout time.Duration `yaml:"command_timeout"`
 	} `yaml:"telegram"`
 	Allora struct {
-		RPC     string   `yaml:"rpc"`
-		API     string   `yaml:"api"`
-		Address []string `yaml:"address"`
+		RPC               string        `yaml:"rpc"`
+		API               string        `yaml:"api"`
+		Forge             string        `yaml:"forge"`
+		Address           []string      `yaml:"address"`
+		CacheTTL          time.Duration `yaml:"cache_ttl"`
+		Concurrency       int           `yaml:"concurrency"`
+		EmissionsVersions []string      `yaml:"emissions_versions"`
+		// Aliases maps a short name to a tracked address for use in commands
+		Aliases map[string]string `yaml:"aliases"`
+		// Roles maps an address to the roles it runs per topic, inferer when not listed
+		Roles map[string]map[int][]string `yaml:"roles"`
+		Retry struct {
+			Attempts  int           `yaml:"attempts"`
+			BaseDelay time.Duration `yaml:"base_delay"`
+			MaxDelay  time.Duration `yaml:"max_delay"`
+			Deadline  time.Duration `yaml:"deadline"`
+		} `yaml:"retry"`
 	} `yaml:"allora"`
+	Checker struct {
+		Interval string        `yaml:"interval"`
+		Cron     string        `yaml:"cron"`
+		Timeout  time.Duration `yaml:"timeout"`
+		// Mode is "ticker" (default) or "blocks" to follow new blocks over the RPC websocket
+		Mode        string `yaml:"mode"`
+		EveryBlocks int    `yaml:"every_blocks"`
+		EpochTopics []int  `yaml:"epoch_topics"`
+	} `yaml:"checker"`
+	Alerts struct {
+		// MarginThreshold warns when a score margin above the lowest active score drops below it
+		MarginThreshold float64 `yaml:"margin_threshold"`
+		// ProjectionEpochs warns when the margin trend reaches zero within this many epochs.
+		// It defaults to 3 when unset, 0 turns the projection off.
+		ProjectionEpochs *int `yaml:"projection_epochs"`
+		// MarginSamples is the number of recent margins used for the trend
+		MarginSamples int `yaml:"margin_samples"`
+		// OutlierZScore flags a submitted value whose robust z-score of deviation exceeds it
+		OutlierZScore float64 `yaml:"outlier_z_score"`
+		// MissingChecks is the number of consecutive checks a worker must be missing before alerting
+		MissingChecks int `yaml:"missing_checks"`
+	} `yaml:"alerts"`
+	History struct {
+		// Backend is "file" (default) for JSON Lines per address or "bolt" for an embedded database.
+		// The running bot locks a bolt database, so the export and backfill commands need it stopped.
+		Backend string `yaml:"backend"`
+		// Path is the history directory of the file backend or the database file of the bolt backend
+		Path string `yaml:"path"`
+	} `yaml:"history"`
 }
 
 func Load() (*Config, error) {
@@ -31,5 +84,108 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	for address, topics := range config.Allora.Roles {
+		for topicID, roles := range topics {
+			for _, role := range roles {
+				switch models.Role(role) {
+				case models.RoleInferer, models.RoleForecaster, models.RoleReputer:
+				default:
+					return nil, fmt.Errorf("unknown role %q for %s in topic %d", role, address, topicID)
+				}
+			}
+		}
+	}
+	if config.Telegram.CommandTimeout == 0 {
+		config.Telegram.CommandTimeout = 2 * time.Minute
+	}
+	if config.Allora.Forge == "" {
+		config.Allora.Forge = "https://forge.allora.network"
+	}
+	if config.Allora.CacheTTL == 0 {
+		config.Allora.CacheTTL = 30 * time.Second
+	}
+	if config.Allora.Concurrency <= 0 {
+		config.Allora.Concurrency = 4
+	}
+	if config.Checker.Interval == "" && config.Checker.Cron == "" {
+		config.Checker.Interval = "1m"
+	}
+	if config.Checker.Mode == "blocks" && config.Checker.EveryBlocks == 0 && len(config.Checker.EpochTopics) == 0 {
+		config.Checker.EveryBlocks = 10
+	}
+	if config.Alerts.ProjectionEpochs == nil {
+		projectionEpochs := 3
+		config.Alerts.ProjectionEpochs = &projectionEpochs
+	}
+	if config.Alerts.MarginSamples < 3 {
+		config.Alerts.MarginSamples = 12
+	}
+	if config.Alerts.OutlierZScore == 0 {
+		config.Alerts.OutlierZScore = 3.5
+	}
+	if config.Alerts.MissingChecks <= 0 {
+		config.Alerts.MissingChecks = 1
+	}
+	switch config.History.Backend {
+	case "":
+		config.History.Backend = HistoryBackendFile
+	case HistoryBackendFile, HistoryBackendBolt:
+	default:
+		return nil, fmt.Errorf("unknown history backend %q", config.History.Backend)
+	}
+	if config.History.Path == "" {
+		config.History.Path = "history"
+		if config.History.Backend == HistoryBackendBolt {
+			config.History.Path = "history/history.db"
+		}
+	}
+	if config.Checker.Timeout == 0 {
+		config.Checker.Timeout = 5 * time.Minute
+	}
+
 	return &config, nil
 }
+
+// RolesFor returns the roles an address runs in a topic
+func (c *Config) RolesFor(address string, topicID int) []models.Role {
+	names := c.Allora.Roles[address][topicID]
+	if len(names) == 0 {
+		return []models.Role{models.RoleInferer}
+	}
+
+	roles := make([]models.Role, len(names))
+	for i, name := range names {
+		roles[i] = models.Role(name)
+	}
+	return roles
+}
+
+// ResolveAddress returns the address for an alias, or the argument itself if it is a tracked address
+func (c *Config) ResolveAddress(arg string) (string, bool) {
+	if address, ok := c.Allora.Aliases[arg]; ok {
+		return address, true
+	}
+	for _, address := range c.Allora.Address {
+		if address == arg {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// LabelFor returns the alias of an address, or a shortened address if it has none
+func (c *Config) LabelFor(address string) string {
+	label := ""
+	for alias, aliased := range c.Allora.Aliases {
+		if aliased == address && (label == "" || alias < label) {
+			label = alias
+		}
+	}
+	if label != "" {
+		return label
+	}
+	if len(address) > 16 {
+		return address[:10] + "…" + address[len(address)-4:]
+	}
+	return address
+}
@@ -22,15 +22,37 @@ type AlloraUser struct {
 	Competitions     []Competition `json:"competitions"`
 }
 
+// Role is the kind of worker an address runs in a topic
+type Role string
+
+const (
+	RoleInferer    Role = "inferer"
+	RoleForecaster Role = "forecaster"
+	RoleReputer    Role = "reputer"
+)
+
 type Competition struct {
-	ID                      int     `json:"id"`
-	Name                    string  `json:"name"`
-	TopicID                 int     `json:"topic_id"`
-	Points                  float64 `json:"points"`
-	Ranking                 int     `json:"ranking"`
-	Weight                  float64 `json:"-"`
-	WeightRank              int     `json:"-"`
-	TotalWeightParticipants int     `json:"-"`
+	ID                      int          `json:"id"`
+	Name                    string       `json:"name"`
+	TopicID                 int          `json:"topic_id"`
+	Points                  float64      `json:"points"`
+	Ranking                 int          `json:"ranking"`
+	Weight                  float64      `json:"-"`
+	WeightRank              int          `json:"-"`
+	TotalWeightParticipants int          `json:"-"`
+	Roles                   []RoleStatus `json:"-"`
+	// MissingWeight is set when the worker is absent from the topic's latest inferer weights
+	MissingWeight bool `json:"-"`
+}
+
+// RoleStatus is the active set status of an address for one role in a topic
+type RoleStatus struct {
+	Role        Role
+	Active      bool
+	Score       float64
+	LowestScore float64
+	Margin      float64
+	BlockHeight string
 }
 
 // Add new structures for API responses
@@ -51,6 +73,9 @@ type UserHistory struct {
 	TotalPoints  float64       `json:"total_points"`
 	Ranking      int           `json:"ranking"`
 	Competitions []CompHistory `json:"competitions"`
+	// BlockHeight is set on history backfilled from past chain state, which has no forge
+	// points or rankings
+	BlockHeight int64 `json:"block_height,omitempty"`
 }
 
 type CompHistory struct {
@@ -60,6 +85,14 @@ type CompHistory struct {
 	Weight                  float64 `json:"weight"`
 	WeightRank              int     `json:"weight_rank"`
 	TotalWeightParticipants int     `json:"total_weight_participants"`
+	// Score is the inferer score EMA, only recorded by backfill
+	Score float64 `json:"score,omitempty"`
+}
+
+// HistoryRecord is a stored snapshot of an address as exported
+type HistoryRecord struct {
+	Address string `json:"address"`
+	UserHistory
 }
 
 // Add new structure for ranking display
@@ -77,6 +110,8 @@ type UserRankInfo struct {
 type NetworkInferencesResponse struct {
 	NetworkInferences struct {
 		TopicID       string         `json:"topic_id"`
+		CombinedValue string         `json:"combined_value"`
+		NaiveValue    string         `json:"naive_value"`
 		InfererValues []InfererValue `json:"inferer_values"`
 	} `json:"network_inferences"`
 	InfererWeights []InfererWeight `json:"inferer_weights"`
@@ -113,3 +148,93 @@ type CompChangeInfo struct {
 	WeightDiff     float64 `json:"weight_diff"`
 	WeightRankDiff int     `json:"weight_rank_diff"`
 }
+
+// RankSnapshot is a complete view of every tracked address collected in one pass
+type RankSnapshot struct {
+	Timestamp time.Time
+	Users     []UserRankInfo
+	Changes   map[string]RankChangeInfo
+	UserData  map[string]*AlloraUser
+	// Baselines holds the time of the history each address was compared against
+	Baselines map[string]time.Time
+}
+
+// BlockResponse is the Cosmos REST response of a block query
+type BlockResponse struct {
+	Block struct {
+		Header BlockHeader `json:"header"`
+	} `json:"block"`
+}
+
+type BlockHeader struct {
+	Height string    `json:"height"`
+	Time   time.Time `json:"time"`
+}
+
+// Topic epoch information returned by the emissions topic query
+type TopicResponse struct {
+	Topic Topic `json:"topic"`
+}
+
+type Topic struct {
+	ID             string `json:"id"`
+	EpochLength    string `json:"epoch_length"`
+	EpochLastEnded string `json:"epoch_last_ended"`
+}
+
+// Eviction alert kinds
+const (
+	EvictionWarning   = "warning"
+	EvictionEvicted   = "evicted"
+	EvictionRecovered = "recovered"
+)
+
+// EvictionAlert reports a worker approaching or leaving the active set of a topic
+type EvictionAlert struct {
+	Kind            string
+	Address         string
+	Name            string
+	CompID          int
+	CompName        string
+	TopicID         int
+	Role            Role
+	Margin          float64
+	EpochsRemaining float64
+	Reason          string
+}
+
+// ValueAnalysis compares a worker's submitted value with the network's combined inference
+type ValueAnalysis struct {
+	Worker            string
+	Value             float64
+	CombinedValue     float64
+	NaiveValue        float64
+	Deviation         float64
+	RelativeDeviation float64
+	// Percentile is the share of inferers whose deviation is larger, 100 being closest to the network
+	Percentile float64
+	ZScore     float64
+	Outlier    bool
+}
+
+// ValueAlert reports a worker whose submitted value became or stopped being an outlier
+type ValueAlert struct {
+	Address   string
+	Name      string
+	CompID    int
+	CompName  string
+	TopicID   int
+	Analysis  ValueAnalysis
+	Recovered bool
+}
+
+// PresenceAlert reports a worker that disappeared from or reappeared in a topic's inferer weights
+type PresenceAlert struct {
+	Address       string
+	Name          string
+	CompID        int
+	CompName      string
+	TopicID       int
+	MissingChecks int
+	Recovered     bool
+}
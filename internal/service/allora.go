@@ -1,126 +1,76 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"math"
 	"strconv"
 	"time"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
 )
 
-const (
-	version = "v8"
-)
-
+// AlloraService provides rank and weight logic on top of an Allora API client
 type AlloraService struct {
-	client *http.Client
-	api    string
+	client.Client
+	inferences *InferenceCache
 }
 
-// NewAlloraService creates a new instance of AlloraService with configured HTTP client
-func NewAlloraService(api string) *AlloraService {
-	return &AlloraService{
-		client: &http.Client{
-			Timeout: time.Second * 60,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 100,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		api: api,
+// NewAlloraService creates a new instance of AlloraService using the given client.
+// Topic network inferences are shared between callers for cacheTTL.
+func NewAlloraService(c client.Client, cacheTTL time.Duration) *AlloraService {
+	s := &AlloraService{
+		Client: c,
 	}
+	s.inferences = NewInferenceCache(cacheTTL, s.fetchTopicInferences)
+	return s
 }
 
-// FetchUserData retrieves user data from the Allora API
-func (s *AlloraService) FetchUserData(address string) (*models.AlloraUser, error) {
-	url := fmt.Sprintf("https://forge.allora.network/api/upshot-api-proxy/allora/forge/user/%s", address)
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user data: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+// UpdateCompetitionWeights updates the weights for competitions
+func (s *AlloraService) UpdateCompetitionWeights(ctx context.Context, userData *models.AlloraUser, address string) error {
+	for i := range userData.Competitions {
+		topicID := strconv.Itoa(userData.Competitions[i].TopicID)
+		inferences, err := s.TopicInferences(ctx, topicID)
+		if err != nil {
+			return err
+		}
 
-	var response models.AlloraResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		s.updateCompetitionWeight(&userData.Competitions[i], inferences, address)
 	}
-
-	return &response.Data, nil
+	return nil
 }
 
-// FetchScore retrieves the score for a specific topic and address
-func (s *AlloraService) FetchScore(topicID, address string) (*models.ScoreData, error) {
-	url := fmt.Sprintf("%s/emissions/%s/inferer_score_ema/%s/%s", s.api, version, topicID, address)
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch score: %w", err)
-	}
-	defer resp.Body.Close()
+// inferenceCycleKey is the context key of the inference cache of a check cycle
+type inferenceCycleKey struct{}
 
-	var scoreResp models.ScoreResponse
-	if err := json.NewDecoder(resp.Body).Decode(&scoreResp); err != nil {
-		return nil, fmt.Errorf("failed to decode score response: %w", err)
+// WithInferenceCycle returns a context in which every TopicInferences call sees the same data
+// for a topic, so all addresses of a cycle are ranked against one view. Cycles read through
+// the cache shared for Allora.CacheTTL. A ctx that already has a cycle is returned as is.
+func (s *AlloraService) WithInferenceCycle(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(inferenceCycleKey{}).(*InferenceCache); ok {
+		return ctx
 	}
-
-	return &scoreResp.Score, nil
+	// Entries of a cycle never expire, the cycle is dropped with its ctx
+	return context.WithValue(ctx, inferenceCycleKey{}, NewInferenceCache(math.MaxInt64, s.inferences.Get))
 }
 
-// FetchLowestScore retrieves the lowest score for a specific topic
-func (s *AlloraService) FetchLowestScore(topicID string) (*models.ScoreData, error) {
-	url := fmt.Sprintf("%s/emissions/%s/current_lowest_inferer_score/%s", s.api, version, topicID)
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch lowest score: %w", err)
+// TopicInferences returns the ranked network inferences of a topic shared across addresses
+func (s *AlloraService) TopicInferences(ctx context.Context, topicID string) (*TopicInferences, error) {
+	if cycle, ok := ctx.Value(inferenceCycleKey{}).(*InferenceCache); ok {
+		return cycle.Get(ctx, topicID)
 	}
-	defer resp.Body.Close()
-
-	var scoreResp models.ScoreResponse
-	if err := json.NewDecoder(resp.Body).Decode(&scoreResp); err != nil {
-		return nil, fmt.Errorf("failed to decode lowest score response: %w", err)
-	}
-
-	return &scoreResp.Score, nil
+	return s.inferences.Get(ctx, topicID)
 }
 
-// FetchNetworkInferences retrieves network inferences for a specific topic
-func (s *AlloraService) FetchNetworkInferences(topicID string) (*models.NetworkInferencesResponse, error) {
-	url := fmt.Sprintf("%s/emissions/%s/latest_network_inferences/%s", s.api, version, topicID)
-	resp, err := s.client.Get(url)
+// fetchTopicInferences retrieves the network inferences of a topic and ranks its weights
+func (s *AlloraService) fetchTopicInferences(ctx context.Context, topicID string) (*TopicInferences, error) {
+	networkInferences, err := s.FetchNetworkInferences(ctx, topicID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch network inferences: %w", err)
+		return nil, fmt.Errorf("failed to fetch network inferences for topic %s: %w", topicID, err)
 	}
-	defer resp.Body.Close()
 
-	var result models.NetworkInferencesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode network inferences response: %w", err)
-	}
-
-	return &result, nil
-}
-
-// UpdateCompetitionWeights updates the weights for competitions
-func (s *AlloraService) UpdateCompetitionWeights(userData *models.AlloraUser, address string) error {
-	for i := range userData.Competitions {
-		topicID := strconv.Itoa(userData.Competitions[i].TopicID)
-		networkInferences, err := s.FetchNetworkInferences(topicID)
-		if err != nil {
-			return fmt.Errorf("failed to fetch network inferences for topic %s: %w", topicID, err)
-		}
-
-		weights := s.processWeights(networkInferences.InfererWeights)
-		s.updateCompetitionWeight(&userData.Competitions[i], weights, address)
-	}
-	return nil
+	return newTopicInferences(topicID, networkInferences, s.processWeights(networkInferences.InfererWeights)), nil
 }
 
 // processWeights converts and sorts weight information
@@ -156,41 +106,57 @@ func (s *AlloraService) processWeights(weights []models.InfererWeight) []models.
 }
 
 // updateCompetitionWeight updates weight information for a specific competition
-func (s *AlloraService) updateCompetitionWeight(comp *models.Competition, weights []models.WeightRank, address string) {
-	for _, w := range weights {
-		if w.Worker == address {
-			comp.Weight = w.Weight
-			comp.WeightRank = w.Rank
-			comp.TotalWeightParticipants = len(weights)
-			break
-		}
+func (s *AlloraService) updateCompetitionWeight(comp *models.Competition, inferences *TopicInferences, address string) {
+	w, ok := inferences.Lookup(address)
+	comp.MissingWeight = !ok
+	if ok {
+		comp.Weight = w.Weight
+		comp.WeightRank = w.Rank
+		comp.TotalWeightParticipants = len(inferences.Weights)
 	}
 }
 
-// IsActive checks if a user is active in a specific competition
-func (s *AlloraService) IsActive(topicID, address string) (bool, float64, error) {
-	userScore, err := s.FetchScore(topicID, address)
+// IsActive checks if a user is active in a specific competition for a role
+func (s *AlloraService) IsActive(ctx context.Context, role models.Role, topicID, address string) (bool, float64, error) {
+	status, err := s.RoleStatus(ctx, role, topicID, address)
 	if err != nil {
 		return false, 0, err
 	}
 
-	lowestScore, err := s.FetchLowestScore(topicID)
+	return status.Active, status.Margin, nil
+}
+
+// RoleStatus compares the score EMA of a role with the topic's current lowest score
+func (s *AlloraService) RoleStatus(ctx context.Context, role models.Role, topicID, address string) (*models.RoleStatus, error) {
+	userScore, err := s.FetchScore(ctx, role, topicID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	lowestScore, err := s.FetchLowestScore(ctx, role, topicID)
 	if err != nil {
-		return false, 0, err
+		return nil, err
 	}
 
 	userScoreFloat, _ := strconv.ParseFloat(userScore.Score, 64)
 	lowestScoreFloat, _ := strconv.ParseFloat(lowestScore.Score, 64)
 
-	return userScoreFloat > lowestScoreFloat, userScoreFloat - lowestScoreFloat, nil
+	return &models.RoleStatus{
+		Role:        role,
+		Active:      userScoreFloat > lowestScoreFloat,
+		Score:       userScoreFloat,
+		LowestScore: lowestScoreFloat,
+		Margin:      userScoreFloat - lowestScoreFloat,
+		BlockHeight: userScore.BlockHeight,
+	}, nil
 }
 
 // GetUserInfo is an alias for FetchUserData
-func (s *AlloraService) GetUserInfo(address string) (*models.AlloraUser, error) {
-	return s.FetchUserData(address)
+func (s *AlloraService) GetUserInfo(ctx context.Context, address string) (*models.AlloraUser, error) {
+	return s.FetchUserData(ctx, address)
 }
 
 // GetNetworkInferences is an alias for FetchNetworkInferences
-func (s *AlloraService) GetNetworkInferences(topicID string) (*models.NetworkInferencesResponse, error) {
-	return s.FetchNetworkInferences(topicID)
+func (s *AlloraService) GetNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error) {
+	return s.FetchNetworkInferences(ctx, topicID)
 }
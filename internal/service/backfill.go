@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
+)
+
+// Backfiller writes history for past block heights from historical chain state.
+// Past heights only have chain data, so backfilled snapshots hold inferer score EMAs
+// and inference weights but no forge points or rankings.
+type Backfiller struct {
+	alloraService  *AlloraService
+	blocks         client.BlockFetcher
+	historyService *HistoryService
+	concurrency    int
+}
+
+// NewBackfiller creates a new instance of Backfiller
+func NewBackfiller(alloraService *AlloraService, blocks client.BlockFetcher, historyService *HistoryService, cfg *config.Config) *Backfiller {
+	return &Backfiller{
+		alloraService:  alloraService,
+		blocks:         blocks,
+		historyService: historyService,
+		concurrency:    cfg.Allora.Concurrency,
+	}
+}
+
+// LatestHeight returns the height of the latest block
+func (b *Backfiller) LatestHeight(ctx context.Context) (int64, error) {
+	header, err := b.blocks.FetchBlock(ctx, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch latest block: %w", err)
+	}
+	return strconv.ParseInt(header.Height, 10, 64)
+}
+
+// Backfill walks back from height to down to from in steps and stores a snapshot of the address
+// at every height. The competitions are the ones the address is currently registered in.
+// It stops at the first height whose block cannot be fetched, usually because the node pruned it,
+// and returns the number of snapshots stored.
+func (b *Backfiller) Backfill(ctx context.Context, address string, from, to, step int64) (int, error) {
+	if step <= 0 {
+		return 0, fmt.Errorf("step must be positive")
+	}
+	// Height 0 reads the latest state, which must not be stored as past history
+	if from <= 0 || to <= 0 {
+		return 0, fmt.Errorf("block heights must be positive, got %d to %d", from, to)
+	}
+
+	user, err := b.alloraService.FetchUserData(ctx, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch competitions of %s: %w", address, err)
+	}
+
+	var histories []models.UserHistory
+	for height := to; height >= from; height -= step {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+
+		header, err := b.blocks.FetchBlock(ctx, height)
+		if err != nil {
+			log.Printf("Stopping backfill of %s at height %d: %v", address, height, err)
+			break
+		}
+
+		history := models.UserHistory{
+			Timestamp:   header.Time,
+			BlockHeight: height,
+		}
+		history.Competitions = b.competitionsAt(client.WithHeight(ctx, height), user.Competitions, address)
+		if len(history.Competitions) == 0 {
+			log.Printf("No chain data for %s at height %d", address, height)
+			continue
+		}
+		histories = append(histories, history)
+		log.Printf("Backfilled %s at height %d (%s)", address, height, header.Time.Format("2006-01-02 15:04"))
+	}
+
+	if err := b.historyService.Import(ctx, address, histories); err != nil {
+		return 0, err
+	}
+	return len(histories), ctx.Err()
+}
+
+// competitionsAt fetches the inferer score EMA and inference weight of every competition
+// at the height set on ctx, leaving out competitions without data
+func (b *Backfiller) competitionsAt(ctx context.Context, competitions []models.Competition, address string) []models.CompHistory {
+	var mu sync.Mutex
+	var result []models.CompHistory
+	forEachBounded(ctx, len(competitions), b.concurrency, func(i int) {
+		comp := competitions[i]
+		topicID := strconv.Itoa(comp.TopicID)
+		history := models.CompHistory{ID: comp.ID}
+		found := false
+
+		if response, err := b.alloraService.FetchNetworkInferences(ctx, topicID); err != nil {
+			log.Printf("Error fetching weights of topic %s at height %d: %v", topicID, client.HeightFrom(ctx), err)
+		} else {
+			inferences := newTopicInferences(topicID, response, b.alloraService.processWeights(response.InfererWeights))
+			if w, ok := inferences.Lookup(address); ok {
+				history.Weight = w.Weight
+				history.WeightRank = w.Rank
+				history.TotalWeightParticipants = len(inferences.Weights)
+				found = true
+			}
+		}
+
+		if score, err := b.alloraService.FetchScore(ctx, models.RoleInferer, topicID, address); err != nil {
+			log.Printf("Error fetching score of topic %s at height %d: %v", topicID, client.HeightFrom(ctx), err)
+		} else if value, err := strconv.ParseFloat(score.Score, 64); err == nil {
+			history.Score = value
+			found = true
+		}
+
+		if found {
+			mu.Lock()
+			result = append(result, history)
+			mu.Unlock()
+		}
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
+)
+
+// topicEpoch holds the epoch schedule of a topic
+type topicEpoch struct {
+	length    int64
+	lastEnded int64
+}
+
+// BlockTrigger runs the scheduler's job on new blocks and falls back to polling
+// while the block subscription is down
+type BlockTrigger struct {
+	subscriber  *client.BlockSubscriber
+	topics      client.TopicFetcher
+	scheduler   *Scheduler
+	everyBlocks int64
+	epochTopics []int
+	mu          sync.Mutex
+	epochs      map[int]topicEpoch
+}
+
+// NewBlockTrigger creates a new instance of BlockTrigger.
+// The job runs every everyBlocks blocks and whenever an epoch of one of epochTopics ends.
+func NewBlockTrigger(subscriber *client.BlockSubscriber, topics client.TopicFetcher, scheduler *Scheduler, everyBlocks int, epochTopics []int) *BlockTrigger {
+	return &BlockTrigger{
+		subscriber:  subscriber,
+		topics:      topics,
+		scheduler:   scheduler,
+		everyBlocks: int64(everyBlocks),
+		epochTopics: epochTopics,
+		epochs:      make(map[int]topicEpoch),
+	}
+}
+
+// Run blocks and follows new blocks until ctx is cancelled, reconnecting with backoff
+func (t *BlockTrigger) Run(ctx context.Context) {
+	t.refreshEpochs(ctx)
+
+	var (
+		stopFallback context.CancelFunc
+		retry        int
+	)
+	startFallback := func() {
+		if stopFallback != nil {
+			return
+		}
+		log.Println("Block subscription unavailable, falling back to scheduled checks")
+		var fallbackCtx context.Context
+		fallbackCtx, stopFallback = context.WithCancel(ctx)
+		go t.scheduler.Run(fallbackCtx)
+	}
+	stopFallbackLoop := func() {
+		if stopFallback == nil {
+			return
+		}
+		stopFallback()
+		stopFallback = nil
+		log.Println("Block subscription restored, stopped scheduled checks")
+	}
+	defer stopFallbackLoop()
+
+	for {
+		err := t.subscriber.Subscribe(ctx, func(height int64) {
+			stopFallbackLoop()
+			retry = 0
+			t.onBlock(ctx, height)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error following new blocks: %v", err)
+		startFallback()
+
+		delay := reconnectDelay(retry)
+		retry++
+		log.Printf("Reconnecting to block subscription in %s", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// onBlock runs the job when the height matches the block interval or an epoch boundary
+func (t *BlockTrigger) onBlock(ctx context.Context, height int64) {
+	reason := ""
+	if t.everyBlocks > 0 && height%t.everyBlocks == 0 {
+		reason = "block interval"
+	}
+
+	t.mu.Lock()
+	for topicID, epoch := range t.epochs {
+		if epoch.length > 0 && height > epoch.lastEnded && (height-epoch.lastEnded)%epoch.length == 0 {
+			reason = "epoch end of topic " + strconv.Itoa(topicID)
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	if reason == "" {
+		return
+	}
+
+	log.Printf("Block %d reached %s", height, reason)
+	// RunOnce skips the block if the previous check is still running
+	go func() {
+		t.scheduler.RunOnce(ctx)
+		t.refreshEpochs(ctx)
+	}()
+}
+
+// refreshEpochs loads the epoch schedule of every watched topic
+func (t *BlockTrigger) refreshEpochs(ctx context.Context) {
+	for _, topicID := range t.epochTopics {
+		topic, err := t.topics.FetchTopic(ctx, strconv.Itoa(topicID))
+		if err != nil {
+			log.Printf("Error fetching epoch of topic %d: %v", topicID, err)
+			continue
+		}
+
+		length, _ := strconv.ParseInt(topic.EpochLength, 10, 64)
+		lastEnded, _ := strconv.ParseInt(topic.EpochLastEnded, 10, 64)
+
+		t.mu.Lock()
+		t.epochs[topicID] = topicEpoch{length: length, lastEnded: lastEnded}
+		t.mu.Unlock()
+	}
+}
+
+// reconnectDelay returns the exponential backoff before the given reconnect attempt
+func reconnectDelay(retry int) time.Duration {
+	delay := time.Second << uint(retry)
+	if delay <= 0 || delay > time.Minute {
+		delay = time.Minute
+	}
+	return delay
+}
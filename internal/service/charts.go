@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/chart"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// buildRankChart turns stored history into panels of overall ranking, points and
+// weight rank per competition. A compID of 0 charts every competition.
+func buildRankChart(title string, histories []models.UserHistory, compID int) *chart.Chart {
+	ranking := chart.Series{Name: "ranking"}
+	points := chart.Series{Name: "points"}
+	weightRanks := make(map[int]*chart.Series)
+
+	for _, history := range histories {
+		// Backfilled history has no forge ranking or points
+		if history.Ranking > 0 {
+			ranking.Points = append(ranking.Points, chart.Point{Time: history.Timestamp, Value: float64(history.Ranking)})
+			points.Points = append(points.Points, chart.Point{Time: history.Timestamp, Value: history.TotalPoints})
+		}
+
+		for _, comp := range history.Competitions {
+			if comp.WeightRank <= 0 || (compID != 0 && comp.ID != compID) {
+				continue
+			}
+			series, ok := weightRanks[comp.ID]
+			if !ok {
+				series = &chart.Series{Name: fmt.Sprintf("#%d", comp.ID)}
+				weightRanks[comp.ID] = series
+			}
+			series.Points = append(series.Points, chart.Point{Time: history.Timestamp, Value: float64(comp.WeightRank)})
+		}
+	}
+
+	// Competitions in ID order so colors stay stable between charts
+	ids := make([]int, 0, len(weightRanks))
+	for id := range weightRanks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	weightPanel := chart.Panel{Title: "Weight rank", Invert: true, Integer: true}
+	for _, id := range ids {
+		weightPanel.Series = append(weightPanel.Series, *weightRanks[id])
+	}
+
+	c := &chart.Chart{Title: title}
+	if len(ranking.Points) > 0 {
+		c.Panels = append(c.Panels, chart.Panel{Title: "Overall ranking", Series: []chart.Series{ranking}, Invert: true, Integer: true})
+	}
+	if len(points.Points) > 0 {
+		c.Panels = append(c.Panels, chart.Panel{Title: "Total points", Series: []chart.Series{points}})
+	}
+	if len(weightPanel.Series) > 0 {
+		c.Panels = append(c.Panels, weightPanel)
+	}
+	return c
+}
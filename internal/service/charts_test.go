@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// chartHistories returns a day of history for two competitions, starting with a backfilled snapshot
+func chartHistories() []models.UserHistory {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	histories := []models.UserHistory{{
+		Timestamp:   start,
+		BlockHeight: 1000,
+		Competitions: []models.CompHistory{
+			{ID: 1, WeightRank: 12, Score: 0.4},
+			{ID: 2, WeightRank: 30, Score: 0.1},
+		},
+	}}
+
+	ranks := []int{40, 38, 38, 35, 31, 33, 29, 27}
+	for i, rank := range ranks {
+		histories = append(histories, models.UserHistory{
+			Timestamp:   start.Add(time.Duration(i+1) * 3 * time.Hour),
+			TotalPoints: float64(100 + i*15),
+			Ranking:     rank,
+			Competitions: []models.CompHistory{
+				{ID: 1, WeightRank: 12 - i},
+				{ID: 2, WeightRank: 30 - i%3*4},
+			},
+		})
+	}
+	return histories
+}
+
+func TestBuildRankChartGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		compID int
+		panels int
+	}{
+		{name: "rank_chart_all", compID: 0, panels: 3},
+		{name: "rank_chart_competition", compID: 2, panels: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := buildRankChart("worker history", chartHistories(), tt.compID)
+			if len(c.Panels) != tt.panels {
+				t.Fatalf("got %d panels, want %d", len(c.Panels), tt.panels)
+			}
+
+			var buf bytes.Buffer
+			if err := c.Render(&buf); err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".png")
+			if *updateGolden {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run go test -update to create it: %v", err)
+			}
+			// Compare pixels rather than bytes so PNG encoder changes do not break the test
+			if !samePixels(t, buf.Bytes(), want) {
+				t.Errorf("chart differs from %s, run go test -update and review the image", golden)
+			}
+		})
+	}
+}
+
+func TestBuildRankChartSkipsBackfilledRanking(t *testing.T) {
+	c := buildRankChart("worker history", chartHistories()[:1], 0)
+	if len(c.Panels) != 1 || c.Panels[0].Title != "Weight rank" {
+		t.Fatalf("got panels %+v, want only the weight rank panel", c.Panels)
+	}
+}
+
+// samePixels reports whether two encoded PNG images have the same size and pixels
+func samePixels(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	imgA, err := png.Decode(bytes.NewReader(a))
+	if err != nil {
+		t.Fatalf("failed to decode rendered chart: %v", err)
+	}
+	imgB, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("failed to decode golden chart: %v", err)
+	}
+
+	if imgA.Bounds() != imgB.Bounds() {
+		return false
+	}
+	bounds := imgA.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !samePixel(imgA, imgB, x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// samePixel reports whether two images have the same color at x, y
+func samePixel(a, b image.Image, x, y int) bool {
+	r1, g1, b1, a1 := a.At(x, y).RGBA()
+	r2, g2, b2, a2 := b.At(x, y).RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
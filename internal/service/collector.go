@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
+)
+
+// RankCollector gathers user data, topic weights and rank changes for all tracked addresses
+type RankCollector struct {
+	alloraService  *AlloraService
+	historyService *HistoryService
+	config         *config.Config
+	addresses      []string
+	concurrency    int
+}
+
+// NewRankCollector creates a new instance of RankCollector.
+// At most Allora.Concurrency requests are in flight at the same time.
+func NewRankCollector(alloraService *AlloraService, historyService *HistoryService, cfg *config.Config) *RankCollector {
+	return &RankCollector{
+		alloraService:  alloraService,
+		historyService: historyService,
+		config:         cfg,
+		addresses:      cfg.Allora.Address,
+		concurrency:    cfg.Allora.Concurrency,
+	}
+}
+
+// Collect fetches the current state of every tracked address and diffs it against
+// the named baseline, so each consumer only sees changes since it last looked
+func (c *RankCollector) Collect(ctx context.Context, baseline string) *models.RankSnapshot {
+	return c.collect(ctx, func(address string) (*models.UserHistory, error) {
+		return c.historyService.LoadBaseline(ctx, baseline, address)
+	})
+}
+
+// CollectSince fetches the current state of every tracked address and diffs it against
+// the history nearest to since, which is the earliest history when it starts after since
+func (c *RankCollector) CollectSince(ctx context.Context, since time.Time) *models.RankSnapshot {
+	return c.collect(ctx, func(address string) (*models.UserHistory, error) {
+		return c.historyService.HistoryNearest(ctx, address, since)
+	})
+}
+
+// collect fetches the current state of every tracked address and diffs it against the history returned by previous
+func (c *RankCollector) collect(ctx context.Context, previous func(address string) (*models.UserHistory, error)) *models.RankSnapshot {
+	snapshot := &models.RankSnapshot{
+		Timestamp: time.Now(),
+		Changes:   make(map[string]models.RankChangeInfo),
+		UserData:  make(map[string]*models.AlloraUser),
+		Baselines: make(map[string]time.Time),
+	}
+
+	users := c.CollectUsers(ctx, c.addresses)
+	for i, user := range users {
+		if user == nil {
+			continue
+		}
+		address := c.addresses[i]
+
+		prevHistory, err := previous(address)
+		if err != nil {
+			log.Printf("Error loading history for %s: %v", address, err)
+		}
+		if prevHistory != nil {
+			snapshot.Baselines[address] = prevHistory.Timestamp
+		}
+
+		snapshot.UserData[address] = user
+		snapshot.Changes[address] = calculateChanges(user, prevHistory)
+		snapshot.Users = append(snapshot.Users, models.UserRankInfo{
+			Name:         fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+			Username:     user.Username,
+			Ranking:      user.Ranking,
+			Points:       user.TotalPoints,
+			BadgeName:    user.BadgeName,
+			Address:      address,
+			Competitions: user.Competitions,
+		})
+	}
+
+	// Sort users by ranking
+	sort.SliceStable(snapshot.Users, func(i, j int) bool {
+		return snapshot.Users[i].Ranking < snapshot.Users[j].Ranking
+	})
+
+	return snapshot
+}
+
+// CollectUsers fetches user data, topic weights and role status of the given addresses.
+// Results are in the order of addresses, with nil for addresses that could not be fetched.
+func (c *RankCollector) CollectUsers(ctx context.Context, addresses []string) []*models.AlloraUser {
+	// Addresses and their topics share one limiter, so the limit holds for the whole cycle,
+	// and one view of each topic's inferences
+	lim := newLimiter(c.concurrency)
+	ctx = c.alloraService.WithInferenceCycle(ctx)
+
+	// Results are stored by index so the output does not depend on completion order
+	users := make([]*models.AlloraUser, len(addresses))
+	forEach(len(addresses), func(i int) {
+		users[i] = c.collectAddress(ctx, lim, addresses[i])
+	})
+	return users
+}
+
+// collectAddress fetches user data, topic weights and role status of a single address
+func (c *RankCollector) collectAddress(ctx context.Context, lim limiter, address string) *models.AlloraUser {
+	var user *models.AlloraUser
+	var err error
+	if !lim.do(ctx, func() { user, err = c.alloraService.FetchUserData(ctx, address) }) {
+		return nil
+	}
+	if errors.Is(err, client.ErrNotFound) {
+		log.Printf("Address %s is not registered on the forge, skipping", address)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error fetching user data for %s: %v", address, err)
+		return nil
+	}
+
+	c.fillWeights(ctx, lim, user, address)
+	c.fillRoles(ctx, lim, user, address)
+	return user
+}
+
+// Save stores every user of the snapshot as the latest history
+func (c *RankCollector) Save(ctx context.Context, snapshot *models.RankSnapshot) {
+	for _, user := range snapshot.Users {
+		if err := c.historyService.SaveHistory(ctx, user.Address, snapshot.UserData[user.Address]); err != nil {
+			log.Printf("Error saving history for %s: %v", user.Address, err)
+		}
+	}
+}
+
+// SaveBaseline stores every user of the snapshot as the named baseline
+func (c *RankCollector) SaveBaseline(ctx context.Context, baseline string, snapshot *models.RankSnapshot) {
+	for _, user := range snapshot.Users {
+		if err := c.historyService.SaveBaseline(ctx, baseline, user.Address, snapshot.UserData[user.Address], snapshot.Timestamp); err != nil {
+			log.Printf("Error saving %s baseline for %s: %v", baseline, user.Address, err)
+		}
+	}
+}
+
+// fillWeights updates weight information for each competition of the user
+func (c *RankCollector) fillWeights(ctx context.Context, lim limiter, user *models.AlloraUser, address string) {
+	forEach(len(user.Competitions), func(i int) {
+		topicID := strconv.Itoa(user.Competitions[i].TopicID)
+		var inferences *TopicInferences
+		var err error
+		if !lim.do(ctx, func() { inferences, err = c.alloraService.TopicInferences(ctx, topicID) }) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching weights for %s: %v", address, err)
+			return
+		}
+		c.alloraService.updateCompetitionWeight(&user.Competitions[i], inferences, address)
+	})
+}
+
+// fillRoles updates the active set status of every role the address runs in each competition
+func (c *RankCollector) fillRoles(ctx context.Context, lim limiter, user *models.AlloraUser, address string) {
+	forEach(len(user.Competitions), func(i int) {
+		comp := &user.Competitions[i]
+		topicID := strconv.Itoa(comp.TopicID)
+		for _, role := range c.config.RolesFor(address, comp.TopicID) {
+			var status *models.RoleStatus
+			var err error
+			if !lim.do(ctx, func() { status, err = c.alloraService.RoleStatus(ctx, role, topicID, address) }) {
+				return
+			}
+			if err != nil {
+				log.Printf("Error fetching %s status for %s in topic %s: %v", role, address, topicID, err)
+				continue
+			}
+			comp.Roles = append(comp.Roles, *status)
+		}
+	})
+}
+
+// calculateChanges calculates the differences between current and previous data.
+// Without previous data every difference is zero so the address is still reported.
+func calculateChanges(current *models.AlloraUser, prev *models.UserHistory) models.RankChangeInfo {
+	changes := models.RankChangeInfo{
+		CompChanges: make(map[int]models.CompChangeInfo),
+	}
+
+	if prev == nil {
+		for _, comp := range current.Competitions {
+			changes.CompChanges[comp.ID] = models.CompChangeInfo{}
+		}
+		return changes
+	}
+
+	// Backfilled history only holds chain data, so forge rankings and points are not compared
+	forge := prev.Ranking > 0
+	if forge {
+		changes.OverallRankChanged = current.Ranking != prev.Ranking
+		changes.OverallRankDiff = prev.Ranking - current.Ranking
+		changes.PointsDiff = current.TotalPoints - prev.TotalPoints
+	}
+
+	for _, comp := range current.Competitions {
+		change := models.CompChangeInfo{}
+		for _, prevComp := range prev.Competitions {
+			if comp.ID == prevComp.ID {
+				change = models.CompChangeInfo{
+					WeightDiff:     comp.Weight - prevComp.Weight,
+					WeightRankDiff: prevComp.WeightRank - comp.WeightRank,
+				}
+				if forge {
+					change.RankChanged = prevComp.Ranking != comp.Ranking
+					change.RankDiff = prevComp.Ranking - comp.Ranking
+					change.PointsDiff = comp.Points - prevComp.Points
+				}
+				break
+			}
+		}
+		changes.CompChanges[comp.ID] = change
+	}
+
+	return changes
+}
+
+// hasRankChanges reports whether any overall or competition rank changed
+func hasRankChanges(changes map[string]models.RankChangeInfo) bool {
+	for _, change := range changes {
+		if change.OverallRankChanged {
+			return true
+		}
+		for _, compChange := range change.CompChanges {
+			if compChange.RankChanged {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// evictionKey identifies the margin series of one role of an address in a topic
+type evictionKey struct {
+	address string
+	topicID int
+	role    models.Role
+}
+
+// marginPoint is a score margin observed at a block height
+type marginPoint struct {
+	height int64
+	margin float64
+}
+
+// marginSeries holds recent margins and the last alert sent for them
+type marginSeries struct {
+	points  []marginPoint
+	active  bool
+	warned  bool
+	evicted bool
+}
+
+// epochInfo caches the epoch length of a topic
+type epochInfo struct {
+	length    int64
+	fetchedAt time.Time
+}
+
+// EvictionMonitor tracks score margins over time and reports workers at risk of leaving the active set
+type EvictionMonitor struct {
+	alloraService    *AlloraService
+	threshold        float64
+	projectionEpochs int
+	samples          int
+	mu               sync.Mutex
+	series           map[evictionKey]*marginSeries
+	epochs           map[int]epochInfo
+}
+
+// NewEvictionMonitor creates a new instance of EvictionMonitor
+func NewEvictionMonitor(alloraService *AlloraService, cfg *config.Config) *EvictionMonitor {
+	projectionEpochs := 0
+	if cfg.Alerts.ProjectionEpochs != nil {
+		projectionEpochs = *cfg.Alerts.ProjectionEpochs
+	}
+
+	return &EvictionMonitor{
+		alloraService:    alloraService,
+		threshold:        cfg.Alerts.MarginThreshold,
+		projectionEpochs: projectionEpochs,
+		samples:          cfg.Alerts.MarginSamples,
+		series:           make(map[evictionKey]*marginSeries),
+		epochs:           make(map[int]epochInfo),
+	}
+}
+
+// Observe records the margins of a snapshot and returns alerts for state transitions
+func (m *EvictionMonitor) Observe(ctx context.Context, snapshot *models.RankSnapshot) []models.EvictionAlert {
+	var alerts []models.EvictionAlert
+
+	for _, user := range snapshot.Users {
+		for _, comp := range user.Competitions {
+			for _, status := range comp.Roles {
+				key := evictionKey{address: user.Address, topicID: comp.TopicID, role: status.Role}
+				alert := m.observe(ctx, key, status)
+				if alert == nil {
+					continue
+				}
+
+				alert.Address = user.Address
+				alert.Name = user.Name
+				alert.CompID = comp.ID
+				alert.CompName = comp.Name
+				alert.TopicID = comp.TopicID
+				alert.Role = status.Role
+				alert.Margin = status.Margin
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+
+	return alerts
+}
+
+// observe appends a margin to its series and returns an alert if the eviction state changed
+func (m *EvictionMonitor) observe(ctx context.Context, key evictionKey, status models.RoleStatus) *models.EvictionAlert {
+	height, _ := strconv.ParseInt(status.BlockHeight, 10, 64)
+
+	m.mu.Lock()
+	series, ok := m.series[key]
+	if !ok {
+		// State is kept in memory only, so a worker already out of the active set at startup
+		// is reported on the first observation instead of being taken as the baseline
+		m.series[key] = &marginSeries{
+			points:  []marginPoint{{height: height, margin: status.Margin}},
+			active:  status.Active,
+			evicted: !status.Active,
+		}
+		m.mu.Unlock()
+		if !status.Active {
+			return &models.EvictionAlert{Kind: models.EvictionEvicted, Reason: "score is below the lowest active score"}
+		}
+		return nil
+	}
+
+	last := series.points[len(series.points)-1]
+	if height == 0 || height != last.height {
+		series.points = append(series.points, marginPoint{height: height, margin: status.Margin})
+		if len(series.points) > m.samples {
+			series.points = series.points[len(series.points)-m.samples:]
+		}
+	}
+	points := append([]marginPoint(nil), series.points...)
+	m.mu.Unlock()
+
+	// Projection needs the epoch length, fetched outside the lock
+	epochsRemaining := -1.0
+	if status.Active && m.projectionEpochs > 0 {
+		epochsRemaining = m.projectEpochs(ctx, key.topicID, points)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wasActive := series.active
+	series.active = status.Active
+
+	switch {
+	case !status.Active && wasActive:
+		series.evicted = true
+		series.warned = false
+		return &models.EvictionAlert{Kind: models.EvictionEvicted, Reason: "score fell below the lowest active score"}
+	case status.Active && series.evicted:
+		series.evicted = false
+		return &models.EvictionAlert{Kind: models.EvictionRecovered, Reason: "score is back above the lowest active score"}
+	case !status.Active:
+		return nil
+	}
+
+	var reason string
+	if m.threshold > 0 && status.Margin < m.threshold {
+		reason = fmt.Sprintf("margin is below %.5f", m.threshold)
+	} else if epochsRemaining >= 0 && epochsRemaining <= float64(m.projectionEpochs) {
+		reason = fmt.Sprintf("trend reaches the floor in %.1f epochs", epochsRemaining)
+	}
+
+	if reason == "" {
+		series.warned = false
+		return nil
+	}
+	if series.warned {
+		return nil
+	}
+	series.warned = true
+	return &models.EvictionAlert{Kind: models.EvictionWarning, EpochsRemaining: epochsRemaining, Reason: reason}
+}
+
+// projectEpochs returns the number of epochs until the margin trend reaches zero,
+// or -1 if the margin is not shrinking or there is not enough data
+func (m *EvictionMonitor) projectEpochs(ctx context.Context, topicID int, points []marginPoint) float64 {
+	if len(points) < 3 {
+		return -1
+	}
+
+	slope, ok := marginSlope(points)
+	if !ok || slope >= 0 {
+		return -1
+	}
+
+	epochLength := m.epochLength(ctx, topicID)
+	if epochLength <= 0 {
+		return -1
+	}
+
+	margin := points[len(points)-1].margin
+	blocks := margin / -slope
+	return blocks / float64(epochLength)
+}
+
+// marginSlope fits a least squares line through the margins and returns the change per block
+func marginSlope(points []marginPoint) (float64, bool) {
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	base := points[0].height
+	for _, p := range points {
+		x := float64(p.height - base)
+		sumX += x
+		sumY += p.margin
+		sumXY += x * p.margin
+		sumXX += x * x
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denom, true
+}
+
+// epochLength returns the cached epoch length of a topic, refreshing it hourly
+func (m *EvictionMonitor) epochLength(ctx context.Context, topicID int) int64 {
+	m.mu.Lock()
+	info, ok := m.epochs[topicID]
+	m.mu.Unlock()
+	if ok && time.Since(info.fetchedAt) < time.Hour {
+		return info.length
+	}
+
+	topic, err := m.alloraService.FetchTopic(ctx, strconv.Itoa(topicID))
+	if err != nil {
+		log.Printf("Error fetching epoch length of topic %d: %v", topicID, err)
+		return info.length
+	}
+
+	length, _ := strconv.ParseInt(topic.EpochLength, 10, 64)
+	m.mu.Lock()
+	m.epochs[topicID] = epochInfo{length: length, fetchedAt: time.Now()}
+	m.mu.Unlock()
+	return length
+}
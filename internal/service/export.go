@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// Export formats of stored history
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// exportColumns is the CSV header, one row per competition of every snapshot
+var exportColumns = []string{
+	"address",
+	"timestamp",
+	"block_height",
+	"total_points",
+	"ranking",
+	"competition_id",
+	"competition_points",
+	"competition_ranking",
+	"weight",
+	"weight_rank",
+	"total_weight_participants",
+	"score",
+}
+
+// Export writes the history of the addresses taken between from and to in the given format
+// and returns the number of snapshots written
+func (s *HistoryService) Export(ctx context.Context, w io.Writer, addresses []string, from, to time.Time, format string) (int, error) {
+	var records []models.HistoryRecord
+	for _, address := range addresses {
+		histories, err := s.store.Between(ctx, address, from, to)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load history of %s: %w", address, err)
+		}
+		for _, history := range histories {
+			records = append(records, models.HistoryRecord{Address: address, UserHistory: history})
+		}
+	}
+
+	switch format {
+	case ExportCSV:
+		return len(records), writeCSV(w, records)
+	case ExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if records == nil {
+			records = []models.HistoryRecord{}
+		}
+		if err := encoder.Encode(records); err != nil {
+			return 0, fmt.Errorf("failed to encode history: %w", err)
+		}
+		return len(records), nil
+	default:
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// writeCSV writes one row per competition of every record, or a single row without
+// competition columns for a record without competitions
+func writeCSV(w io.Writer, records []models.HistoryRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportColumns); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, record := range records {
+		user := []string{
+			record.Address,
+			record.Timestamp.Format(time.RFC3339),
+			formatHeight(record.BlockHeight),
+			strconv.FormatFloat(record.TotalPoints, 'f', -1, 64),
+			strconv.Itoa(record.Ranking),
+		}
+		if len(record.Competitions) == 0 {
+			if err := writer.Write(append(user, "", "", "", "", "", "", "")); err != nil {
+				return fmt.Errorf("failed to write csv: %w", err)
+			}
+			continue
+		}
+
+		for _, comp := range record.Competitions {
+			row := append(append([]string(nil), user...),
+				strconv.Itoa(comp.ID),
+				strconv.FormatFloat(comp.Points, 'f', -1, 64),
+				strconv.Itoa(comp.Ranking),
+				strconv.FormatFloat(comp.Weight, 'f', -1, 64),
+				strconv.Itoa(comp.WeightRank),
+				strconv.Itoa(comp.TotalWeightParticipants),
+				strconv.FormatFloat(comp.Score, 'f', -1, 64),
+			)
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// formatHeight formats a block height, leaving it empty for history collected live
+func formatHeight(height int64) string {
+	if height == 0 {
+		return ""
+	}
+	return strconv.FormatInt(height, 10)
+}
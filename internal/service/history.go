@@ -1,48 +1,98 @@
 package service
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"context"
+	"io"
 	"time"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/models"
 )
 
+// HistoryService records the data of each address over time in a HistoryStore
 type HistoryService struct {
-	baseDir string
+	store HistoryStore
 }
 
-func NewHistoryService(baseDir string) *HistoryService {
+func NewHistoryService(store HistoryStore) *HistoryService {
 	return &HistoryService{
-		baseDir: baseDir,
+		store: store,
 	}
 }
 
-// LoadHistory loads historical data for a specific address
-func (s *HistoryService) LoadHistory(address string) (*models.UserHistory, error) {
-	filename := filepath.Join(s.baseDir, fmt.Sprintf("history_%s.json", address))
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to read history file: %w", err)
+// LoadHistory loads the latest historical data for a specific address
+func (s *HistoryService) LoadHistory(ctx context.Context, address string) (*models.UserHistory, error) {
+	return s.store.Latest(ctx, address)
+}
+
+// HistoryNearest returns the latest snapshot taken at or before t. When history starts after t
+// it returns the earliest snapshot instead, or nil if there is none.
+func (s *HistoryService) HistoryNearest(ctx context.Context, address string, t time.Time) (*models.UserHistory, error) {
+	history, err := s.store.At(ctx, address, t)
+	if err != nil || history != nil {
+		return history, err
+	}
+
+	histories, err := s.store.Between(ctx, address, t, time.Now())
+	if err != nil || len(histories) == 0 {
+		return nil, err
+	}
+	return &histories[0], nil
+}
+
+// HistoryBetween returns every snapshot taken between from and to inclusive, oldest first
+func (s *HistoryService) HistoryBetween(ctx context.Context, address string, from, to time.Time) ([]models.UserHistory, error) {
+	return s.store.Between(ctx, address, from, to)
+}
+
+// CompetitionHistory returns the snapshots of one competition taken between from and to inclusive
+func (s *HistoryService) CompetitionHistory(ctx context.Context, address string, compID int, from, to time.Time) ([]models.UserHistory, error) {
+	return s.store.CompetitionBetween(ctx, address, compID, from, to)
+}
+
+// SaveHistory appends the current data of a specific address to its history
+func (s *HistoryService) SaveHistory(ctx context.Context, address string, userData *models.AlloraUser) error {
+	return s.store.Append(ctx, address, newUserHistory(userData, time.Now()))
+}
+
+// Import merges snapshots of any age into the history of an address
+func (s *HistoryService) Import(ctx context.Context, address string, histories []models.UserHistory) error {
+	return s.store.Import(ctx, address, histories)
+}
+
+// LoadBaseline loads the snapshot a named consumer last compared against,
+// falling back to the latest history if the consumer has no baseline yet
+func (s *HistoryService) LoadBaseline(ctx context.Context, name, address string) (*models.UserHistory, error) {
+	baseline, err := s.store.LoadBaseline(ctx, name, address)
+	if err != nil || baseline != nil {
+		return baseline, err
 	}
+	return s.store.Latest(ctx, address)
+}
+
+// SaveBaseline stores user data as the baseline of a named consumer
+func (s *HistoryService) SaveBaseline(ctx context.Context, name, address string, userData *models.AlloraUser, timestamp time.Time) error {
+	return s.store.SaveBaseline(ctx, name, address, newUserHistory(userData, timestamp))
+}
 
-	var history models.UserHistory
-	if err := json.Unmarshal(data, &history); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal history data: %w", err)
+// Backup writes a consistent copy of the history store to w and returns the bytes written,
+// or ErrBackupUnsupported if the backend cannot be backed up while in use
+func (s *HistoryService) Backup(w io.Writer) (int64, error) {
+	backuper, ok := s.store.(HistoryBackuper)
+	if !ok {
+		return 0, ErrBackupUnsupported
 	}
+	return backuper.Backup(w)
+}
 
-	return &history, nil
+// Close closes the underlying store
+func (s *HistoryService) Close() error {
+	return s.store.Close()
 }
 
-// SaveHistory saves historical data for a specific address
-func (s *HistoryService) SaveHistory(address string, userData *models.AlloraUser) error {
+// newUserHistory converts user data into a history snapshot
+func newUserHistory(userData *models.AlloraUser, timestamp time.Time) models.UserHistory {
 	history := models.UserHistory{
-		Timestamp:    time.Now(),
+		Timestamp:    timestamp,
 		TotalPoints:  userData.TotalPoints,
 		Ranking:      userData.Ranking,
 		Competitions: make([]models.CompHistory, len(userData.Competitions)),
@@ -59,15 +109,5 @@ func (s *HistoryService) SaveHistory(address string, userData *models.AlloraUser
 		}
 	}
 
-	filename := filepath.Join(s.baseDir, fmt.Sprintf("history_%s.json", address))
-	data, err := json.MarshalIndent(history, "", "    ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal history data: %w", err)
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write history file: %w", err)
-	}
-
-	return nil
+	return history
 }
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Top level buckets of the bolt history store.
+// snapshots holds a bucket per address keyed by timestamp,
+// competitions holds a bucket per competition with a bucket per address keyed by timestamp,
+// baselines holds a bucket per baseline name keyed by address.
+var (
+	bucketSnapshots    = []byte("snapshots")
+	bucketCompetitions = []byte("competitions")
+	bucketBaselines    = []byte("baselines")
+)
+
+// BoltHistoryStore stores history in an embedded bbolt database
+type BoltHistoryStore struct {
+	db *bolt.DB
+}
+
+var _ HistoryStore = (*BoltHistoryStore)(nil)
+
+// OpenBoltHistoryStore opens or creates the bolt history database at path
+func OpenBoltHistoryStore(path string) (*BoltHistoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// Fail fast instead of blocking when another process holds the database
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("history database %s is in use by another process: %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSnapshots, bucketCompetitions, bucketBaselines} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+
+	return &BoltHistoryStore{db: db}, nil
+}
+
+// OpenReadOnlyBoltHistoryStore opens an existing bolt history database for reading.
+// bbolt still waits for the exclusive lock of a process writing the database,
+// so this fails while the bot is running.
+func OpenReadOnlyBoltHistoryStore(path string) (*BoltHistoryStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("history database %s is in use by the bot, stop it or use /export in Telegram: %w", path, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	return &BoltHistoryStore{db: db}, nil
+}
+
+// Append stores a snapshot and indexes its competitions
+func (s *BoltHistoryStore) Append(ctx context.Context, address string, history models.UserHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history data: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return putHistory(tx, address, history, data, true)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Import stores snapshots of any age in one transaction, keys keep them in time order
+func (s *BoltHistoryStore) Import(ctx context.Context, address string, histories []models.UserHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, history := range histories {
+			data, err := json.Marshal(history)
+			if err != nil {
+				return err
+			}
+			if err := putHistory(tx, address, history, data, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import history: %w", err)
+	}
+	return nil
+}
+
+// putHistory stores an encoded snapshot and indexes its competitions.
+// Without overwrite an existing snapshot with the same timestamp is kept.
+func putHistory(tx *bolt.Tx, address string, history models.UserHistory, data []byte, overwrite bool) error {
+	key := timeKey(history.Timestamp)
+	snapshots, err := tx.Bucket(bucketSnapshots).CreateBucketIfNotExists([]byte(address))
+	if err != nil {
+		return err
+	}
+	if !overwrite && snapshots.Get(key) != nil {
+		return nil
+	}
+	if err := snapshots.Put(key, data); err != nil {
+		return err
+	}
+
+	competitions := tx.Bucket(bucketCompetitions)
+	for _, comp := range history.Competitions {
+		compData, err := json.Marshal(comp)
+		if err != nil {
+			return err
+		}
+		byComp, err := competitions.CreateBucketIfNotExists([]byte(strconv.Itoa(comp.ID)))
+		if err != nil {
+			return err
+		}
+		byAddress, err := byComp.CreateBucketIfNotExists([]byte(address))
+		if err != nil {
+			return err
+		}
+		if err := byAddress.Put(key, compData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest returns the most recent snapshot of an address
+func (s *BoltHistoryStore) Latest(ctx context.Context, address string) (*models.UserHistory, error) {
+	return s.At(ctx, address, maxKeyTime)
+}
+
+// At returns the latest snapshot taken at or before t
+func (s *BoltHistoryStore) At(ctx context.Context, address string, t time.Time) (*models.UserHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var found *models.UserHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSnapshots).Bucket([]byte(address))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		key := timeKey(t)
+		k, v := cursor.Seek(key)
+		if k == nil {
+			k, v = cursor.Last()
+		} else if !bytes.Equal(k, key) {
+			k, v = cursor.Prev()
+		}
+		if k == nil {
+			return nil
+		}
+
+		var history models.UserHistory
+		if err := json.Unmarshal(v, &history); err != nil {
+			return fmt.Errorf("failed to unmarshal history data: %w", err)
+		}
+		found = &history
+		return nil
+	})
+	return found, err
+}
+
+// Between returns every snapshot taken between from and to inclusive
+func (s *BoltHistoryStore) Between(ctx context.Context, address string, from, to time.Time) ([]models.UserHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []models.UserHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSnapshots).Bucket([]byte(address))
+		if bucket == nil {
+			return nil
+		}
+
+		return rangeKeys(bucket, from, to, func(_, v []byte) error {
+			var history models.UserHistory
+			if err := json.Unmarshal(v, &history); err != nil {
+				return fmt.Errorf("failed to unmarshal history data: %w", err)
+			}
+			result = append(result, history)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// CompetitionBetween reads the competition index between from and to inclusive
+func (s *BoltHistoryStore) CompetitionBetween(ctx context.Context, address string, compID int, from, to time.Time) ([]models.UserHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []models.UserHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		byComp := tx.Bucket(bucketCompetitions).Bucket([]byte(strconv.Itoa(compID)))
+		if byComp == nil {
+			return nil
+		}
+		byAddress := byComp.Bucket([]byte(address))
+		if byAddress == nil {
+			return nil
+		}
+		snapshots := tx.Bucket(bucketSnapshots).Bucket([]byte(address))
+
+		return rangeKeys(byAddress, from, to, func(k, v []byte) error {
+			var comp models.CompHistory
+			if err := json.Unmarshal(v, &comp); err != nil {
+				return fmt.Errorf("failed to unmarshal competition history: %w", err)
+			}
+
+			// User level fields come from the snapshot sharing the key
+			history := models.UserHistory{Timestamp: keyTime(k)}
+			if snapshots != nil {
+				if data := snapshots.Get(k); data != nil {
+					if err := json.Unmarshal(data, &history); err != nil {
+						return fmt.Errorf("failed to unmarshal history data: %w", err)
+					}
+				}
+			}
+			history.Competitions = []models.CompHistory{comp}
+			result = append(result, history)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// Addresses returns every address with stored snapshots
+func (s *BoltHistoryStore) Addresses(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSnapshots).ForEach(func(k, _ []byte) error {
+			addresses = append(addresses, string(k))
+			return nil
+		})
+	})
+	return addresses, err
+}
+
+// SaveBaseline replaces the named baseline of an address
+func (s *BoltHistoryStore) SaveBaseline(ctx context.Context, name, address string, history models.UserHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(bucketBaselines).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(address), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// LoadBaseline returns the named baseline of an address
+func (s *BoltHistoryStore) LoadBaseline(ctx context.Context, name, address string) (*models.UserHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var found *models.UserHistory
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketBaselines).Bucket([]byte(name))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(address))
+		if data == nil {
+			return nil
+		}
+
+		var history models.UserHistory
+		if err := json.Unmarshal(data, &history); err != nil {
+			return fmt.Errorf("failed to unmarshal baseline: %w", err)
+		}
+		found = &history
+		return nil
+	})
+	return found, err
+}
+
+// Backup writes a consistent copy of the database while it stays in use
+func (s *BoltHistoryStore) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// Close closes the database
+func (s *BoltHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+// rangeKeys calls fn for every entry keyed between from and to inclusive, in time order
+func rangeKeys(bucket *bolt.Bucket, from, to time.Time, fn func(k, v []byte) error) error {
+	end := timeKey(to)
+	cursor := bucket.Cursor()
+	for k, v := cursor.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = cursor.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxKeyTime is the latest time a key can hold
+var maxKeyTime = time.Unix(0, 1<<63-1)
+
+// timeKey encodes a timestamp as a big-endian key so keys sort by time.
+// Times outside the range of a key are clamped to it.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	var nanos int64
+	switch {
+	case t.Before(time.Unix(0, 0)):
+		nanos = 0
+	case t.After(maxKeyTime):
+		nanos = maxKeyTime.UnixNano()
+	default:
+		nanos = t.UnixNano()
+	}
+	binary.BigEndian.PutUint64(key, uint64(nanos))
+	return key
+}
+
+// keyTime decodes a key written by timeKey
+func keyTime(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k)))
+}
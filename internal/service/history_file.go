@@ -0,0 +1,656 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// reverseChunkSize is the number of bytes read at a time when scanning a history file backwards
+const reverseChunkSize = 16 << 10
+
+// FileHistoryStore stores the snapshots of each address as an append-only JSON Lines file.
+// Appends are fsynced, whole-file writes go through a temp file and a rename,
+// and files with corrupt lines are quarantined and rewritten with their valid lines.
+// Writers lock each address with a lock file, so several processes can share the directory.
+type FileHistoryStore struct {
+	baseDir  string
+	readOnly bool
+	mu       sync.Mutex
+	locks    map[string]*sync.RWMutex
+}
+
+var _ HistoryStore = (*FileHistoryStore)(nil)
+
+// NewFileHistoryStore creates a new instance of FileHistoryStore
+func NewFileHistoryStore(baseDir string) *FileHistoryStore {
+	return &FileHistoryStore{
+		baseDir: baseDir,
+		locks:   make(map[string]*sync.RWMutex),
+	}
+}
+
+// NewReadOnlyFileHistoryStore creates a FileHistoryStore that never writes, so it can read
+// the history of a running bot. Corrupt lines are skipped but not quarantined.
+func NewReadOnlyFileHistoryStore(baseDir string) *FileHistoryStore {
+	store := NewFileHistoryStore(baseDir)
+	store.readOnly = true
+	return store
+}
+
+// Latest returns the most recent snapshot of an address, reading only the end of its file
+func (s *FileHistoryStore) Latest(ctx context.Context, address string) (*models.UserHistory, error) {
+	var latest *models.UserHistory
+	err := s.read(ctx, address, s.scanReverse, func(history *models.UserHistory) bool {
+		latest = history
+		return false
+	})
+	return latest, err
+}
+
+// At returns the latest snapshot taken at or before t, reading the file backwards
+// so recent times only read the end of it
+func (s *FileHistoryStore) At(ctx context.Context, address string, t time.Time) (*models.UserHistory, error) {
+	var found *models.UserHistory
+	err := s.read(ctx, address, s.scanReverse, func(history *models.UserHistory) bool {
+		if history.Timestamp.After(t) {
+			return true
+		}
+		found = history
+		return false
+	})
+	return found, err
+}
+
+// Between returns every snapshot taken between from and to inclusive
+func (s *FileHistoryStore) Between(ctx context.Context, address string, from, to time.Time) ([]models.UserHistory, error) {
+	var result []models.UserHistory
+	err := s.read(ctx, address, s.scan, func(history *models.UserHistory) bool {
+		if history.Timestamp.After(to) {
+			return false
+		}
+		if !history.Timestamp.Before(from) {
+			result = append(result, *history)
+		}
+		return true
+	})
+	return result, err
+}
+
+// CompetitionBetween returns the snapshots of one competition taken between from and to inclusive
+func (s *FileHistoryStore) CompetitionBetween(ctx context.Context, address string, compID int, from, to time.Time) ([]models.UserHistory, error) {
+	histories, err := s.Between(ctx, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.UserHistory
+	for _, history := range histories {
+		if filtered, ok := filterCompetition(history, compID); ok {
+			result = append(result, filtered)
+		}
+	}
+	return result, nil
+}
+
+// Addresses returns every address with a history file
+func (s *FileHistoryStore) Addresses(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.baseDir, "history_*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history files: %w", err)
+	}
+
+	addresses := make([]string, len(matches))
+	for i, filename := range matches {
+		addresses[i] = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filename), "history_"), ".jsonl")
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// Append writes one snapshot as a line at the end of the address history
+func (s *FileHistoryStore) Append(ctx context.Context, address string, history models.UserHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history data: %w", err)
+	}
+
+	if err := os.MkdirAll(s.baseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	unlock, err := s.lockWrite(address)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A line left unterminated by a crash would swallow the new line, repair it first
+	torn, err := s.hasTornTail(address)
+	if err != nil {
+		return err
+	}
+	if torn {
+		if err := s.quarantine(address); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(s.filename(address), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync history file: %w", err)
+	}
+
+	return nil
+}
+
+// Import merges snapshots into the history of an address and atomically rewrites it in time order
+func (s *FileHistoryStore) Import(ctx context.Context, address string, histories []models.UserHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if err := os.MkdirAll(s.baseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	unlock, err := s.lockWrite(address)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var merged []models.UserHistory
+	existing := make(map[int64]bool)
+	if _, err := s.scan(ctx, address, func(history *models.UserHistory) bool {
+		merged = append(merged, *history)
+		existing[history.Timestamp.UnixNano()] = true
+		return true
+	}); err != nil {
+		return err
+	}
+	for _, history := range histories {
+		if !existing[history.Timestamp.UnixNano()] {
+			merged = append(merged, history)
+			existing[history.Timestamp.UnixNano()] = true
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	var buf bytes.Buffer
+	for _, history := range merged {
+		data, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history data: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(s.filename(address), buf.Bytes())
+}
+
+// SaveBaseline atomically replaces the named baseline of an address
+func (s *FileHistoryStore) SaveBaseline(ctx context.Context, name, address string, history models.UserHistory) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+
+	filename := s.baselineFilename(name, address)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// LoadBaseline returns the named baseline of an address.
+// A corrupt baseline is quarantined and treated as missing.
+func (s *FileHistoryStore) LoadBaseline(ctx context.Context, name, address string) (*models.UserHistory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	filename := s.baselineFilename(name, address)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var history models.UserHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		if s.readOnly {
+			log.Printf("Skipping corrupt baseline %s: %v", filename, err)
+			return nil, nil
+		}
+		log.Printf("Quarantining corrupt baseline %s: %v", filename, err)
+		if err := os.Rename(filename, filename+".corrupt"); err != nil {
+			return nil, fmt.Errorf("failed to quarantine baseline: %w", err)
+		}
+		return nil, nil
+	}
+	return &history, nil
+}
+
+// Close does nothing, every file is closed after use
+func (s *FileHistoryStore) Close() error {
+	return nil
+}
+
+// Migrate converts single snapshot history_<address>.json files into the append-only format.
+// The old file is kept with a .migrated suffix, or a .corrupt suffix if it cannot be read.
+func (s *FileHistoryStore) Migrate(ctx context.Context) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	matches, err := filepath.Glob(filepath.Join(s.baseDir, "history_*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list history files: %w", err)
+	}
+
+	for _, filename := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		address := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filename), "history_"), ".json")
+		if err := s.migrate(address, filename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrate converts the single snapshot file of an address unless it already has history
+func (s *FileHistoryStore) migrate(address, filename string) error {
+	unlock, err := s.lockWrite(address)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(s.filename(address)); err == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var history models.UserHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Printf("Quarantining unreadable history file %s: %v", filename, err)
+		if err := os.Rename(filename, filename+".corrupt"); err != nil {
+			return fmt.Errorf("failed to quarantine history file: %w", err)
+		}
+		return nil
+	}
+
+	line, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history data: %w", err)
+	}
+	if err := writeFileAtomic(s.filename(address), append(line, '\n')); err != nil {
+		return err
+	}
+	if err := os.Rename(filename, filename+".migrated"); err != nil {
+		return fmt.Errorf("failed to rename migrated history file: %w", err)
+	}
+	log.Printf("Migrated history of %s", address)
+	return nil
+}
+
+// historyScan calls fn for the snapshots of an address until fn returns false
+// and reports whether corrupt lines were skipped
+type historyScan func(ctx context.Context, address string, fn func(history *models.UserHistory) bool) (bool, error)
+
+// read scans the history of an address under its read lock and quarantines it if corrupt lines were found
+func (s *FileHistoryStore) read(ctx context.Context, address string, scan historyScan, fn func(history *models.UserHistory) bool) error {
+	lock := s.lock(address)
+	lock.RLock()
+	corrupt, err := scan(ctx, address, fn)
+	lock.RUnlock()
+	if err != nil || !corrupt || s.readOnly {
+		return err
+	}
+
+	unlock, err := s.lockWrite(address)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.quarantine(address)
+}
+
+// scan calls fn for every valid snapshot of an address in order until fn returns false.
+// Complete lines that fail to decode are skipped and reported as corrupt; an unterminated
+// last line is skipped silently as it may still be being written by another process.
+func (s *FileHistoryStore) scan(ctx context.Context, address string, fn func(history *models.UserHistory) bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(s.filename(address))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer file.Close()
+
+	corrupt := false
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return corrupt, nil
+		}
+		if err != nil {
+			return corrupt, fmt.Errorf("failed to read history file: %w", err)
+		}
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var history models.UserHistory
+		if err := json.Unmarshal(data, &history); err != nil {
+			log.Printf("Skipping corrupt history of %s at line %d: %v", address, line, err)
+			corrupt = true
+			continue
+		}
+		if !fn(&history) {
+			return corrupt, nil
+		}
+	}
+}
+
+// scanReverse is like scan but calls fn from the newest snapshot backwards,
+// reading the file in chunks from its end
+func (s *FileHistoryStore) scanReverse(ctx context.Context, address string, fn func(history *models.UserHistory) bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(s.filename(address))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	// pending holds the bytes from offset to the end of the last line not yet decoded.
+	// Until the first newline is found it holds an unterminated last line, which is skipped.
+	var pending []byte
+	offset := info.Size()
+	terminated := false
+	corrupt := false
+	for {
+		if offset > 0 {
+			if err := ctx.Err(); err != nil {
+				return corrupt, err
+			}
+			n := int64(reverseChunkSize)
+			if offset < n {
+				n = offset
+			}
+			offset -= n
+			chunk := make([]byte, n)
+			if _, err := file.ReadAt(chunk, offset); err != nil {
+				return corrupt, fmt.Errorf("failed to read history file: %w", err)
+			}
+			pending = append(chunk, pending...)
+		}
+
+		if !terminated {
+			i := bytes.LastIndexByte(pending, '\n')
+			if i < 0 {
+				if offset == 0 {
+					return corrupt, nil
+				}
+				continue
+			}
+			pending = pending[:i+1]
+			terminated = true
+		}
+
+		// Decode every line whose start is known, newest first
+		for len(pending) > 0 {
+			body := pending[:len(pending)-1]
+			i := bytes.LastIndexByte(body, '\n')
+			if i < 0 && offset > 0 {
+				break
+			}
+
+			data := bytes.TrimSpace(body[i+1:])
+			pending = pending[:i+1]
+			if len(data) == 0 {
+				continue
+			}
+
+			var history models.UserHistory
+			if err := json.Unmarshal(data, &history); err != nil {
+				log.Printf("Skipping corrupt history of %s: %v", address, err)
+				corrupt = true
+				continue
+			}
+			if !fn(&history) {
+				return corrupt, nil
+			}
+		}
+
+		if offset == 0 {
+			return corrupt, nil
+		}
+	}
+}
+
+// hasTornTail reports whether the history file of an address ends in an unterminated line
+func (s *FileHistoryStore) hasTornTail(address string) (bool, error) {
+	file, err := os.Open(s.filename(address))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read history file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat history file: %w", err)
+	}
+	if info.Size() == 0 {
+		return false, nil
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return last[0] != '\n', nil
+}
+
+// quarantine copies the history file of an address aside with a .corrupt suffix and
+// atomically replaces it with its valid lines. The write lock of the address must be held.
+func (s *FileHistoryStore) quarantine(address string) error {
+	filename := s.filename(address)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var valid bytes.Buffer
+	dropped := 0
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var history models.UserHistory
+		if err := json.Unmarshal(line, &history); err != nil {
+			dropped++
+			continue
+		}
+		valid.Write(line)
+		valid.WriteByte('\n')
+	}
+
+	// Another caller may have repaired the file already, or only a terminating newline is missing
+	if dropped == 0 {
+		if bytes.Equal(valid.Bytes(), data) {
+			return nil
+		}
+		return writeFileAtomic(filename, valid.Bytes())
+	}
+
+	corruptName := fmt.Sprintf("%s.corrupt-%s", filename, time.Now().Format("20060102T150405"))
+	if err := writeFileAtomic(corruptName, data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, valid.Bytes()); err != nil {
+		return err
+	}
+
+	log.Printf("Quarantined corrupt history of %s to %s, dropped %d lines", address, corruptName, dropped)
+	return nil
+}
+
+// lock returns the lock guarding the history file of an address
+func (s *FileHistoryStore) lock(address string) *sync.RWMutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.locks[address]
+	if !ok {
+		lock = &sync.RWMutex{}
+		s.locks[address] = lock
+	}
+	return lock
+}
+
+// lockWrite locks the history of an address against writers in this and other processes,
+// so a backfill running next to the bot cannot drop lines the bot appends
+func (s *FileHistoryStore) lockWrite(address string) (func(), error) {
+	lock := s.lock(address)
+	lock.Lock()
+
+	unlock, err := lockFile(filepath.Join(s.baseDir, "history_"+address+".lock"))
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		lock.Unlock()
+	}, nil
+}
+
+// filename returns the JSON Lines history file of an address
+func (s *FileHistoryStore) filename(address string) string {
+	return filepath.Join(s.baseDir, fmt.Sprintf("history_%s.jsonl", address))
+}
+
+// baselineFilename returns the file of the named baseline of an address
+func (s *FileHistoryStore) baselineFilename(name, address string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+	return filepath.Join(s.baseDir, "baselines", safe, fmt.Sprintf("history_%s.json", address))
+}
+
+// writeFileAtomic writes data to a temp file in the same directory and renames it over filename,
+// so readers and crashes only ever see the old or the new content
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filename, err)
+	}
+
+	// Persist the rename itself; not every platform supports syncing a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package service
+
+// lockFile does nothing where flock is unavailable, writers are then only
+// serialized within one process
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// ErrReadOnly is returned when writing to a history store opened read-only
+var ErrReadOnly = errors.New("history store is read-only")
+
+// ErrBackupUnsupported is returned when the history backend cannot write backups
+var ErrBackupUnsupported = errors.New("history backend does not support backups")
+
+// HistoryBackuper is implemented by history stores that can write a consistent copy
+// of themselves while in use
+type HistoryBackuper interface {
+	Backup(w io.Writer) (int64, error)
+}
+
+var _ HistoryBackuper = (*BoltHistoryStore)(nil)
+
+// HistoryStore persists the snapshots of each address as a time series
+type HistoryStore interface {
+	// Append adds a snapshot to the history of an address
+	Append(ctx context.Context, address string, history models.UserHistory) error
+	// Import merges snapshots of any age into the history of an address in time order,
+	// keeping the existing snapshot when both have the same timestamp
+	Import(ctx context.Context, address string, histories []models.UserHistory) error
+	// Latest returns the most recent snapshot, or nil if there is none
+	Latest(ctx context.Context, address string) (*models.UserHistory, error)
+	// At returns the latest snapshot taken at or before t, or nil if there is none
+	At(ctx context.Context, address string, t time.Time) (*models.UserHistory, error)
+	// Between returns every snapshot taken between from and to inclusive, oldest first
+	Between(ctx context.Context, address string, from, to time.Time) ([]models.UserHistory, error)
+	// CompetitionBetween is like Between but only returns snapshots holding the competition,
+	// with Competitions reduced to that competition
+	CompetitionBetween(ctx context.Context, address string, compID int, from, to time.Time) ([]models.UserHistory, error)
+	// Addresses returns every address with stored history
+	Addresses(ctx context.Context) ([]string, error)
+	// SaveBaseline stores the snapshot a named consumer last compared against
+	SaveBaseline(ctx context.Context, name, address string, history models.UserHistory) error
+	// LoadBaseline returns the named baseline of an address, or nil if there is none
+	LoadBaseline(ctx context.Context, name, address string) (*models.UserHistory, error)
+	Close() error
+}
+
+// OpenHistoryStore opens the configured history store, migrating history files written by older versions.
+// The bolt backend also imports file history kept next to the database, so switching backends keeps it.
+func OpenHistoryStore(ctx context.Context, cfg *config.Config) (HistoryStore, error) {
+	if cfg.History.Backend != config.HistoryBackendBolt {
+		store := NewFileHistoryStore(cfg.History.Path)
+		if err := store.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	store, err := OpenBoltHistoryStore(cfg.History.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := NewFileHistoryStore(filepath.Dir(cfg.History.Path))
+	if err := files.Migrate(ctx); err != nil {
+		store.Close()
+		return nil, err
+	}
+	if err := ImportHistory(ctx, store, files); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// OpenReadOnlyHistoryStore opens the configured history store for reading without migrating
+// or repairing anything, for tools that run next to the bot
+func OpenReadOnlyHistoryStore(cfg *config.Config) (HistoryStore, error) {
+	if cfg.History.Backend != config.HistoryBackendBolt {
+		return NewReadOnlyFileHistoryStore(cfg.History.Path), nil
+	}
+	return OpenReadOnlyBoltHistoryStore(cfg.History.Path)
+}
+
+// ImportHistory copies the history of every address in src that has no history in dst yet
+func ImportHistory(ctx context.Context, dst, src HistoryStore) error {
+	addresses, err := src.Addresses(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		latest, err := dst.Latest(ctx, address)
+		if err != nil {
+			return err
+		}
+		if latest != nil {
+			continue
+		}
+
+		histories, err := src.Between(ctx, address, time.Time{}, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := dst.Import(ctx, address, histories); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// filterCompetition reduces a snapshot to one competition, reporting false if it does not hold it
+func filterCompetition(history models.UserHistory, compID int) (models.UserHistory, bool) {
+	for _, comp := range history.Competitions {
+		if comp.ID == compID {
+			history.Competitions = []models.CompHistory{comp}
+			return history, true
+		}
+	}
+	return history, false
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// TopicInferences holds the latest network inferences of a topic with its ranked weights
+type TopicInferences struct {
+	TopicID   string
+	FetchedAt time.Time
+	Response  *models.NetworkInferencesResponse
+	Weights   []models.WeightRank
+	byWorker  map[string]models.WeightRank
+}
+
+// newTopicInferences ranks the weights of a response and indexes them by worker
+func newTopicInferences(topicID string, response *models.NetworkInferencesResponse, weights []models.WeightRank) *TopicInferences {
+	t := &TopicInferences{
+		TopicID:   topicID,
+		FetchedAt: time.Now(),
+		Response:  response,
+		Weights:   weights,
+		byWorker:  make(map[string]models.WeightRank, len(weights)),
+	}
+	for _, w := range weights {
+		t.byWorker[w.Worker] = w
+	}
+	return t
+}
+
+// Lookup returns the ranked weight of a worker in the topic
+func (t *TopicInferences) Lookup(worker string) (models.WeightRank, bool) {
+	w, ok := t.byWorker[worker]
+	return w, ok
+}
+
+// inferenceFetchTimeout bounds a shared fetch, which no caller can cancel
+const inferenceFetchTimeout = 2 * time.Minute
+
+// inferenceCall is an in-flight fetch that concurrent callers wait on
+type inferenceCall struct {
+	done   chan struct{}
+	result *TopicInferences
+	err    error
+}
+
+// InferenceCache caches topic network inferences for a TTL and coalesces concurrent fetches
+type InferenceCache struct {
+	ttl      time.Duration
+	fetch    func(ctx context.Context, topicID string) (*TopicInferences, error)
+	mu       sync.Mutex
+	entries  map[string]*TopicInferences
+	inflight map[string]*inferenceCall
+}
+
+// NewInferenceCache creates a new instance of InferenceCache
+func NewInferenceCache(ttl time.Duration, fetch func(ctx context.Context, topicID string) (*TopicInferences, error)) *InferenceCache {
+	return &InferenceCache{
+		ttl:      ttl,
+		fetch:    fetch,
+		entries:  make(map[string]*TopicInferences),
+		inflight: make(map[string]*inferenceCall),
+	}
+}
+
+// Get returns the cached inferences of a topic, fetching them once if missing or expired.
+// The shared fetch does not depend on the ctx of the caller that started it, so a cancelled
+// command cannot fail the fetch for every other waiter. Each caller stops waiting when its own ctx is done.
+func (c *InferenceCache) Get(ctx context.Context, topicID string) (*TopicInferences, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[topicID]; ok && time.Since(entry.FetchedAt) < c.ttl {
+		c.mu.Unlock()
+		return entry, nil
+	}
+	call, ok := c.inflight[topicID]
+	if !ok {
+		call = &inferenceCall{done: make(chan struct{})}
+		c.inflight[topicID] = call
+		go c.run(context.WithoutCancel(ctx), topicID, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run performs a shared fetch with its own timeout and stores the result
+func (c *InferenceCache) run(ctx context.Context, topicID string, call *inferenceCall) {
+	ctx, cancel := context.WithTimeout(ctx, inferenceFetchTimeout)
+	defer cancel()
+
+	call.result, call.err = c.fetch(ctx, topicID)
+
+	c.mu.Lock()
+	delete(c.inflight, topicID)
+	if call.err == nil {
+		c.entries[topicID] = call.result
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
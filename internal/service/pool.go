@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachBounded calls fn for indexes 0..n-1 with at most limit calls running at once.
+// No new calls are started once ctx is cancelled; calls already running are awaited.
+func forEachBounded(ctx context.Context, n, limit int, fn func(i int)) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// forEach calls fn for indexes 0..n-1 concurrently and waits for every call.
+// Use a limiter inside fn to bound the work that is actually done at once.
+func forEach(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// limiter caps the number of calls running at once across goroutines,
+// so nested fan-outs can share a single concurrency limit
+type limiter chan struct{}
+
+// newLimiter creates a limiter allowing limit calls at once
+func newLimiter(limit int) limiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return make(limiter, limit)
+}
+
+// do calls fn while holding a slot and reports whether fn was called.
+// fn is not called if ctx is cancelled while waiting for a slot.
+// fn must not call do on the same limiter, or nested calls can wait on each other forever.
+func (l limiter) do(ctx context.Context, fn func()) bool {
+	select {
+	case l <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	defer func() { <-l }()
+
+	fn()
+	return true
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// presenceKey identifies an address in a topic
+type presenceKey struct {
+	address string
+	topicID int
+}
+
+// PresenceMonitor tracks how many consecutive checks a worker has been missing from a topic's weights
+type PresenceMonitor struct {
+	threshold int
+	mu        sync.Mutex
+	missing   map[presenceKey]int
+}
+
+// NewPresenceMonitor creates a new instance of PresenceMonitor
+func NewPresenceMonitor(cfg *config.Config) *PresenceMonitor {
+	return &PresenceMonitor{
+		threshold: cfg.Alerts.MissingChecks,
+		missing:   make(map[presenceKey]int),
+	}
+}
+
+// Observe updates the missing counters from a snapshot and returns alerts for transitions.
+// An alert is sent when a worker reaches the threshold and a recovery notice when it reappears.
+func (m *PresenceMonitor) Observe(snapshot *models.RankSnapshot) []models.PresenceAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alerts []models.PresenceAlert
+	for _, user := range snapshot.Users {
+		for _, comp := range user.Competitions {
+			// Competitions without fetched weights carry no presence information
+			if comp.TotalWeightParticipants == 0 && !comp.MissingWeight {
+				continue
+			}
+
+			key := presenceKey{address: user.Address, topicID: comp.TopicID}
+			count := m.missing[key]
+			alert := models.PresenceAlert{
+				Address:  user.Address,
+				Name:     user.Name,
+				CompID:   comp.ID,
+				CompName: comp.Name,
+				TopicID:  comp.TopicID,
+			}
+
+			if !comp.MissingWeight {
+				delete(m.missing, key)
+				if count >= m.threshold {
+					alert.MissingChecks = count
+					alert.Recovered = true
+					alerts = append(alerts, alert)
+				}
+				continue
+			}
+
+			count++
+			m.missing[key] = count
+			if count == m.threshold {
+				alert.MissingChecks = count
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler runs a job on an interval or cron schedule without overlapping runs
+type Scheduler struct {
+	name     string
+	schedule cron.Schedule
+	job      func(ctx context.Context)
+	mu       sync.Mutex
+}
+
+// NewScheduler creates a new instance of Scheduler.
+// A cron expression takes precedence over the interval when both are set.
+func NewScheduler(name, interval, cronExpr string, job func(ctx context.Context)) (*Scheduler, error) {
+	var schedule cron.Schedule
+	if cronExpr != "" {
+		parsed, err := cron.ParseStandard(cronExpr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cron expression %q: %w", cronExpr, err)
+		}
+		schedule = parsed
+	} else {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse interval %q: %w", interval, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("interval must be positive: %s", interval)
+		}
+		schedule = cron.Every(d)
+	}
+
+	return &Scheduler{
+		name:     name,
+		schedule: schedule,
+		job:      job,
+	}, nil
+}
+
+// Run blocks and executes the job on schedule until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-timer.C:
+			s.RunOnce(ctx)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// RunOnce executes the job immediately unless a run is already in progress
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	if !s.mu.TryLock() {
+		log.Printf("Skipping %s: previous run still in progress", s.name)
+		return
+	}
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	log.Printf("Running %s...", s.name)
+	s.job(ctx)
+	log.Printf("Finished %s in %s", s.name, time.Since(start).Round(time.Millisecond))
+}
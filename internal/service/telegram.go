@@ -1,23 +1,38 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"html"
 	"log"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/config"
 	"github.com/dntjd1097/allora-checker-bot/internal/models"
 	"github.com/dntjd1097/allora-checker-bot/internal/utils"
+	"github.com/dntjd1097/allora-checker-bot/pkg/client"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// topicPageSize is the number of workers shown per page of /topic
+const topicPageSize = 20
+
+// defaultChartWindow is the history shown by /chart without a range
+const defaultChartWindow = 7 * 24 * time.Hour
+
 type TelegramService struct {
 	bot            *tgbotapi.BotAPI
 	config         *config.Config
 	alloraService  *AlloraService
 	historyService *HistoryService
+	collector      *RankCollector
+	eviction       *EvictionMonitor
+	values         *ValueMonitor
+	presence       *PresenceMonitor
 	formatter      *utils.Formatter
 }
 
@@ -27,6 +42,10 @@ func NewTelegramService(bot *tgbotapi.BotAPI, config *config.Config, alloraServi
 		config:         config,
 		alloraService:  alloraService,
 		historyService: historyService,
+		collector:      NewRankCollector(alloraService, historyService, config),
+		eviction:       NewEvictionMonitor(alloraService, config),
+		values:         NewValueMonitor(alloraService, config),
+		presence:       NewPresenceMonitor(config),
 		formatter:      utils.NewFormatter(),
 	}
 }
@@ -48,401 +67,543 @@ func InitBot(token string, maxRetries int) (*tgbotapi.BotAPI, error) {
 	return nil, fmt.Errorf("failed to initialize bot after %d attempts", maxRetries)
 }
 
-// HandleUpdates processes incoming updates and periodic checks
-func (s *TelegramService) HandleUpdates(updates tgbotapi.UpdatesChannel, ticker *time.Ticker) {
+// HandleUpdates processes incoming updates from Telegram until ctx is cancelled
+func (s *TelegramService) HandleUpdates(ctx context.Context) {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+
+	updates := s.bot.GetUpdatesChan(u)
 	for {
 		select {
-		case update := <-updates:
-			if update.Message != nil {
-				s.handleMessage(update.Message)
+		case <-ctx.Done():
+			s.bot.StopReceivingUpdates()
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			if update.Message != nil && update.Message.IsCommand() {
+				s.handleMessage(ctx, update.Message)
+			}
+			if update.CallbackQuery != nil {
+				s.handleCallback(ctx, update.CallbackQuery)
 			}
-		case <-ticker.C:
-			s.CheckRankChanges()
 		}
 	}
 }
 
 // handleMessage processes incoming messages
-func (s *TelegramService) handleMessage(message *tgbotapi.Message) {
-	if message.Command() == "rank" {
-		s.handleRankCommand(message)
+func (s *TelegramService) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Telegram.CommandTimeout)
+	defer cancel()
+
+	switch message.Command() {
+	case "rank":
+		s.handleRankCommand(ctx, message)
+	case "status":
+		s.handleStatusCommand(ctx, message)
+	case "topic":
+		s.handleTopicCommand(ctx, message)
+	case "chart":
+		s.handleChartCommand(ctx, message)
+	case "export":
+		s.handleExportCommand(ctx, message)
+	case "backup":
+		s.handleBackupCommand(ctx, message)
+	case "diag":
+		s.handleDiagCommand(ctx, message)
+	case "help":
+		s.handleHelpCommand(ctx, message)
 	}
 }
 
-// handleRankCommand processes the /rank command
-func (s *TelegramService) handleRankCommand(message *tgbotapi.Message) {
-	changes := make(map[string]models.RankChangeInfo)
-	var users []models.UserRankInfo
-	var userData = make(map[string]*models.AlloraUser) // 임시 저장용 맵 추가
-
-	// 먼저 데이터만 가져오기
-	for _, address := range s.config.Allora.Address {
-		log.Printf("Checking address: %s", address)
-		user, err := s.alloraService.FetchUserData(address)
-		if err != nil {
-			log.Printf("Error fetching user data for %s: %v", address, err)
-			continue
+// handleStatusCommand processes the /status [address|alias] command
+func (s *TelegramService) handleStatusCommand(ctx context.Context, message *tgbotapi.Message) {
+	addresses := s.config.Allora.Address
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		address, ok := s.config.ResolveAddress(arg)
+		if !ok {
+			s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("Unknown address or alias: %s", html.EscapeString(arg)))
+			return
 		}
-		userData[address] = user // 임시 저장
+		addresses = []string{address}
+	}
 
-		// 이전 기록 로드
-		prevHistory, err := s.historyService.LoadHistory(address)
-		if err != nil {
-			log.Printf("Error loading history for %s: %v", address, err)
-		}
+	users := s.collector.CollectUsers(ctx, addresses)
+	if ctx.Err() != nil {
+		log.Printf("Status command aborted: %v", ctx.Err())
+		return
+	}
 
-		// 변경사항 계산
-		if prevHistory != nil {
-			changes[address] = s.calculateChanges(user, prevHistory)
-		}
+	s.sendMessage(ctx, message.Chat.ID, s.formatter.FormatStatusMessage(addresses, users))
+}
 
-		// UserRankInfo 생성
-		userInfo := models.UserRankInfo{
-			Name:         fmt.Sprintf("%s %s", user.FirstName, user.LastName),
-			Username:     user.Username,
-			Ranking:      user.Ranking,
-			Points:       user.TotalPoints,
-			BadgeName:    user.BadgeName,
-			Address:      address,
-			Competitions: user.Competitions,
-		}
-		users = append(users, userInfo)
+// handleTopicCommand processes the /topic <id> [page] command
+func (s *TelegramService) handleTopicCommand(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		s.sendMessage(ctx, message.Chat.ID, "Usage: /topic &lt;id&gt; [page]")
+		return
 	}
 
-	// Sort users by ranking
-	sort.Slice(users, func(i, j int) bool {
-		return users[i].Ranking < users[j].Ranking
-	})
+	topicID, err := strconv.Atoi(args[0])
+	if err != nil {
+		s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("Invalid topic id: %s", html.EscapeString(args[0])))
+		return
+	}
+	page := 1
+	if len(args) > 1 {
+		if p, err := strconv.Atoi(args[1]); err == nil && p > 0 {
+			page = p
+		}
+	}
 
-	// Format message
-	messageText := s.formatter.FormatRankChangeMessage(changes, users)
+	text, markup, err := s.topicPage(ctx, topicID, page)
+	if err != nil {
+		log.Printf("Error fetching topic %d: %v", topicID, err)
+		s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("Failed to fetch topic %d", topicID))
+		return
+	}
 
-	// Create and send message
-	msg := tgbotapi.NewMessage(message.Chat.ID, messageText)
-	msg.ParseMode = "HTML"
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
 	if s.config.Telegram.MessageThread != 0 {
 		msg.ReplyToMessageID = s.config.Telegram.MessageThread
 	}
-
 	if _, err := s.bot.Send(msg); err != nil {
 		log.Printf("Error sending message: %v", err)
 	}
+}
 
-	// Save history after sending the message
-	for _, user := range users {
-		if err := s.historyService.SaveHistory(user.Address, userData[user.Address]); err != nil {
-			log.Printf("Error saving history for %s: %v", user.Address, err)
-		}
+// handleCallback processes inline button presses
+func (s *TelegramService) handleCallback(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Telegram.CommandTimeout)
+	defer cancel()
+
+	// Acknowledge the press so the client stops showing a spinner
+	if _, err := s.bot.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		log.Printf("Error answering callback: %v", err)
 	}
-}
 
-// SendRankChangeNotification sends a notification about rank changes
-func (s *TelegramService) SendRankChangeNotification(changes map[string]models.RankChangeInfo, users []models.UserRankInfo) {
-	// Format message
-	messageText := s.formatter.FormatRankChangeMessage(changes, users)
+	var topicID, page int
+	if _, err := fmt.Sscanf(query.Data, "topic:%d:%d", &topicID, &page); err != nil || query.Message == nil {
+		return
+	}
 
-	// Convert chat ID from string to int64
-	chatID, err := strconv.ParseInt(s.config.Telegram.ChatID, 10, 64)
+	text, markup, err := s.topicPage(ctx, topicID, page)
 	if err != nil {
-		log.Printf("Error parsing chat ID: %v", err)
+		log.Printf("Error fetching topic %d: %v", topicID, err)
 		return
 	}
 
-	// Create and send message
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	msg.ParseMode = "HTML"
-	if s.config.Telegram.MessageThread != 0 {
-		msg.ReplyToMessageID = s.config.Telegram.MessageThread
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.ReplyMarkup = markup
+	if _, err := s.bot.Send(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
 	}
+}
 
-	if _, err := s.bot.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+// topicPage renders one page of a topic's weight leaderboard with navigation buttons
+func (s *TelegramService) topicPage(ctx context.Context, topicID, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	inferences, err := s.alloraService.TopicInferences(ctx, strconv.Itoa(topicID))
+	if err != nil {
+		return "", nil, err
 	}
-}
 
-// CheckRankChanges checks for rank changes and sends notifications
-func (s *TelegramService) CheckRankChanges() {
-	log.Println("Starting rank change check...")
-	changes := make(map[string]models.RankChangeInfo)
-	var users []models.UserRankInfo
-	userData := make(map[string]*models.AlloraUser)
+	labels := make(map[string]string)
+	for _, address := range s.config.Allora.Address {
+		labels[address] = s.config.LabelFor(address)
+	}
+
+	pages := (len(inferences.Weights) + topicPageSize - 1) / topicPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	text := s.formatter.FormatTopicLeaderboard(topicID, inferences.Weights, page, topicPageSize, labels)
 
-	// Fetch data and check for changes
+	// Submitted values of our workers compared with the combined inference
+	var analyses []models.ValueAnalysis
 	for _, address := range s.config.Allora.Address {
-		user, err := s.alloraService.FetchUserData(address)
-		if err != nil {
-			log.Printf("Error fetching user data for %s: %v", address, err)
-			continue
+		if analysis, ok := inferences.AnalyzeValue(address, s.config.Alerts.OutlierZScore); ok {
+			analyses = append(analyses, *analysis)
 		}
-		userData[address] = user
+	}
+	if len(analyses) > 0 {
+		text += "\n" + s.formatter.FormatValueAnalysis(analyses, labels)
+	}
+	if pages == 1 {
+		return text, nil, nil
+	}
 
-		prevHistory, err := s.historyService.LoadHistory(address)
-		if err != nil {
-			log.Printf("Error loading history for %s: %v", address, err)
-			continue
-		}
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", fmt.Sprintf("topic:%d:%d", topicID, page-1)))
+	}
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page, pages), "noop"))
+	if page < pages {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", fmt.Sprintf("topic:%d:%d", topicID, page+1)))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+	return text, &markup, nil
+}
 
-		if prevHistory != nil {
-			change := models.RankChangeInfo{
-				OverallRankChanged: prevHistory.Ranking != user.Ranking,
-				OverallRankDiff:    prevHistory.Ranking - user.Ranking,
-				PointsDiff:         user.TotalPoints - prevHistory.TotalPoints,
-				CompChanges:        make(map[int]models.CompChangeInfo),
-			}
+// handleChartCommand processes the /chart <address|alias> [competition] [range] command
+func (s *TelegramService) handleChartCommand(ctx context.Context, message *tgbotapi.Message) {
+	const usage = "Usage: /chart &lt;address|alias&gt; [competition] [24h|7d|since 2026-10-01]"
 
-			for _, comp := range user.Competitions {
-				for _, prevComp := range prevHistory.Competitions {
-					if comp.ID == prevComp.ID {
-						change.CompChanges[comp.ID] = models.CompChangeInfo{
-							RankChanged:    prevComp.Ranking != comp.Ranking,
-							RankDiff:       prevComp.Ranking - comp.Ranking,
-							PointsDiff:     comp.Points - prevComp.Points,
-							WeightDiff:     comp.Weight - prevComp.Weight,
-							WeightRankDiff: prevComp.WeightRank - comp.WeightRank,
-						}
-						break
-					}
-				}
-			}
-			changes[address] = change
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		s.sendMessage(ctx, message.Chat.ID, usage)
+		return
+	}
+
+	address, ok := s.config.ResolveAddress(args[0])
+	if !ok {
+		s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("Unknown address or alias: %s", html.EscapeString(args[0])))
+		return
+	}
+	args = args[1:]
+
+	compID := 0
+	if len(args) > 0 {
+		if id, err := strconv.Atoi(args[0]); err == nil {
+			compID = id
+			args = args[1:]
 		}
+	}
 
-		users = append(users, models.UserRankInfo{
-			Name:         fmt.Sprintf("%s %s", user.FirstName, user.LastName),
-			Username:     user.Username,
-			Ranking:      user.Ranking,
-			Points:       user.TotalPoints,
-			BadgeName:    user.BadgeName,
-			Address:      address,
-			Competitions: user.Competitions,
-		})
-	}
-
-	// Check if there are any rank changes
-	hasRankChanges := false
-	for _, change := range changes {
-		if change.OverallRankChanged {
-			hasRankChanges = true
-			break
+	now := time.Now()
+	since := now.Add(-defaultChartWindow)
+	if len(args) > 0 {
+		var err error
+		if since, err = utils.ParseSince(strings.Join(args, " "), now); err != nil {
+			s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("%s\n%s", html.EscapeString(err.Error()), usage))
+			return
 		}
-		for _, compChange := range change.CompChanges {
-			if compChange.RankChanged {
-				hasRankChanges = true
-				break
+	}
+
+	var histories []models.UserHistory
+	var err error
+	if compID != 0 {
+		histories, err = s.historyService.CompetitionHistory(ctx, address, compID, since, now)
+	} else {
+		histories, err = s.historyService.HistoryBetween(ctx, address, since, now)
+	}
+	if err != nil {
+		log.Printf("Error loading history for %s: %v", address, err)
+		s.sendMessage(ctx, message.Chat.ID, "Failed to load history")
+		return
+	}
+	if len(histories) < 2 {
+		s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("Not enough history for %s since %s", html.EscapeString(s.config.LabelFor(address)), since.Format("2006-01-02 15:04")))
+		return
+	}
+
+	// The chart font only covers ASCII
+	label := strings.ReplaceAll(s.config.LabelFor(address), "…", "...")
+	title := fmt.Sprintf("%s since %s", label, since.Format("2006-01-02 15:04"))
+	if compID != 0 {
+		title = fmt.Sprintf("%s | competition %d", title, compID)
+	}
+
+	var buf bytes.Buffer
+	if err := buildRankChart(title, histories, compID).Render(&buf); err != nil {
+		log.Printf("Error rendering chart for %s: %v", address, err)
+		s.sendMessage(ctx, message.Chat.ID, "Failed to render chart")
+		return
+	}
+
+	caption := fmt.Sprintf("%s: %d snapshots", html.EscapeString(s.config.LabelFor(address)), len(histories))
+	s.sendPhoto(ctx, message.Chat.ID, tgbotapi.FileBytes{Name: "chart.png", Bytes: buf.Bytes()}, caption)
+}
+
+// handleExportCommand processes the /export [address|alias|all] [range] [csv|json] command
+func (s *TelegramService) handleExportCommand(ctx context.Context, message *tgbotapi.Message) {
+	const usage = "Usage: /export [address|alias|all] [24h|7d|since 2026-10-01] [csv|json]"
+
+	addresses := s.config.Allora.Address
+	name := "all"
+	format := ExportCSV
+	var rangeArgs []string
+	for i, arg := range strings.Fields(message.CommandArguments()) {
+		switch {
+		case arg == ExportCSV || arg == ExportJSON:
+			format = arg
+		case i == 0 && arg == "all":
+		case i == 0:
+			if address, ok := s.config.ResolveAddress(arg); ok {
+				addresses = []string{address}
+				name = arg
+				continue
 			}
-		}
-		if hasRankChanges {
-			break
+			rangeArgs = append(rangeArgs, arg)
+		default:
+			rangeArgs = append(rangeArgs, arg)
 		}
 	}
 
-	// Send notification and save history only if there are rank changes
-	if hasRankChanges {
-		// Send notification
-		for address, user := range userData {
-			if err := s.historyService.SaveHistory(address, user); err != nil {
-				log.Printf("Error saving history for %s: %v", address, err)
-			}
+	now := time.Now()
+	var since time.Time
+	if len(rangeArgs) > 0 {
+		var err error
+		if since, err = utils.ParseSince(strings.Join(rangeArgs, " "), now); err != nil {
+			s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("%s\n%s", html.EscapeString(err.Error()), usage))
+			return
 		}
-		s.SendRankChangeNotification(changes, users)
+	}
 
+	var buf bytes.Buffer
+	count, err := s.historyService.Export(ctx, &buf, addresses, since, now, format)
+	if err != nil {
+		log.Printf("Error exporting history: %v", err)
+		s.sendMessage(ctx, message.Chat.ID, "Failed to export history")
+		return
 	}
+	if count == 0 {
+		s.sendMessage(ctx, message.Chat.ID, "No history to export")
+		return
+	}
+
+	filename := fmt.Sprintf("history_%s_%s.%s", name, now.Format("20060102-1504"), format)
+	caption := fmt.Sprintf("%d snapshots of %s", count, html.EscapeString(name))
+	if !since.IsZero() {
+		caption += fmt.Sprintf(" since %s", since.Format("2006-01-02 15:04"))
+	}
+	s.sendDocument(ctx, message.Chat.ID, tgbotapi.FileBytes{Name: filename, Bytes: buf.Bytes()}, caption)
 }
 
-// calculateChanges calculates the differences between current and previous data
-func (s *TelegramService) calculateChanges(current *models.AlloraUser, prev *models.UserHistory) models.RankChangeInfo {
-	changes := models.RankChangeInfo{
-		OverallRankChanged: current.Ranking != prev.Ranking,
-		OverallRankDiff:    prev.Ranking - current.Ranking,
-		PointsDiff:         current.TotalPoints - prev.TotalPoints,
-		CompChanges:        make(map[int]models.CompChangeInfo),
-	}
-
-	for _, currentComp := range current.Competitions {
-		for _, prevComp := range prev.Competitions {
-			if currentComp.ID == prevComp.ID {
-				changes.CompChanges[currentComp.ID] = models.CompChangeInfo{
-					RankChanged: currentComp.Ranking != prevComp.Ranking,
-					RankDiff:    prevComp.Ranking - currentComp.Ranking,
-					PointsDiff:  currentComp.Points - prevComp.Points,
-					// WeightDiff:     currentComp.Weight - prevComp.Weight,
-					// WeightRankDiff: prevComp.WeightRank - currentComp.WeightRank,
-				}
-				break
-			}
+// handleBackupCommand processes the /backup command, sending a copy of the history database.
+// Backups hold every tracked address, so they are only sent to the alert chat.
+func (s *TelegramService) handleBackupCommand(ctx context.Context, message *tgbotapi.Message) {
+	if strconv.FormatInt(message.Chat.ID, 10) != s.config.Telegram.ChatID {
+		s.sendMessage(ctx, message.Chat.ID, "Backups can only be requested from the alert chat")
+		return
+	}
+
+	var buf bytes.Buffer
+	size, err := s.historyService.Backup(&buf)
+	if errors.Is(err, ErrBackupUnsupported) {
+		s.sendMessage(ctx, message.Chat.ID, "The file history backend has no backup, copy the history directory instead")
+		return
+	}
+	if err != nil {
+		log.Printf("Error backing up history: %v", err)
+		s.sendMessage(ctx, message.Chat.ID, "Failed to back up history")
+		return
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("history_%s.db", now.Format("20060102-1504"))
+	caption := fmt.Sprintf("History backup, %.1f MB", float64(size)/(1<<20))
+	s.sendDocument(ctx, message.Chat.ID, tgbotapi.FileBytes{Name: filename, Bytes: buf.Bytes()}, caption)
+}
+
+// handleDiagCommand processes the /diag command
+func (s *TelegramService) handleDiagCommand(ctx context.Context, message *tgbotapi.Message) {
+	version := "unknown"
+	if detector, ok := s.alloraService.Client.(client.VersionDetector); ok {
+		if v := detector.EmissionsVersion(); v != "" {
+			version = v
 		}
 	}
 
-	return changes
+	schedule := s.config.Checker.Interval
+	if s.config.Checker.Cron != "" {
+		schedule = s.config.Checker.Cron
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🩺 Diagnostics\n")
+	sb.WriteString("─────────────\n")
+	sb.WriteString(fmt.Sprintf("API: %s\n", html.EscapeString(s.config.Allora.API)))
+	sb.WriteString(fmt.Sprintf("Emissions version: %s\n", html.EscapeString(version)))
+	sb.WriteString(fmt.Sprintf("Tracked addresses: %d\n", len(s.config.Allora.Address)))
+	sb.WriteString(fmt.Sprintf("Check schedule: %s\n", html.EscapeString(schedule)))
+
+	s.sendMessage(ctx, message.Chat.ID, sb.String())
 }
 
-// UpdateConfig holds configuration for handling updates
-type UpdateConfig struct {
-	Bot    *tgbotapi.BotAPI
-	Config *config.Config
+// handleHelpCommand processes the /help command
+func (s *TelegramService) handleHelpCommand(ctx context.Context, message *tgbotapi.Message) {
+	s.sendMessage(ctx, message.Chat.ID, `Available commands:
+/rank [1h|24h|7d|since 2026-10-01] - Show current rankings and changes
+/status [address|alias] - Show active set status per topic
+/topic &lt;id&gt; [page] - Show the weight leaderboard of a topic
+/chart &lt;address|alias&gt; [competition] [range] - Chart ranking, points and weight rank history
+/export [address|alias|all] [range] [csv|json] - Export stored history as a file
+/backup - Send a copy of the history database (bolt backend, alert chat only)
+/diag - Show diagnostics
+/help - Show this help message`)
 }
 
-// NewUpdateConfig creates a new UpdateConfig instance
-func NewUpdateConfig(bot *tgbotapi.BotAPI, config *config.Config) *UpdateConfig {
-	return &UpdateConfig{
-		Bot:    bot,
-		Config: config,
+// handleRankCommand processes the /rank [window] command
+func (s *TelegramService) handleRankCommand(ctx context.Context, message *tgbotapi.Message) {
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		s.handleRankWindowCommand(ctx, message, arg)
+		return
+	}
+
+	baseline := rankBaseline(message.Chat.ID)
+	snapshot := s.collector.Collect(ctx, baseline)
+	if ctx.Err() != nil {
+		log.Printf("Rank command aborted: %v", ctx.Err())
+		return
 	}
+
+	messageText := s.formatter.FormatRankChangeMessage(snapshot.Changes, snapshot.Users)
+	s.sendMessage(ctx, message.Chat.ID, messageText)
+
+	// Save history after sending the message, the next /rank in this chat diffs against it
+	s.collector.Save(ctx, snapshot)
+	s.collector.SaveBaseline(ctx, baseline, snapshot)
 }
 
-// HandleUpdates processes incoming updates from Telegram
-func HandleUpdates(updateConfig *UpdateConfig) {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+// handleRankWindowCommand processes /rank 24h, /rank 7d or /rank since 2026-10-01
+func (s *TelegramService) handleRankWindowCommand(ctx context.Context, message *tgbotapi.Message, arg string) {
+	since, err := utils.ParseSince(arg, time.Now())
+	if err != nil {
+		s.sendMessage(ctx, message.Chat.ID, fmt.Sprintf("%s\nUsage: /rank [1h|24h|7d|since 2026-10-01]", html.EscapeString(err.Error())))
+		return
+	}
 
-	updates := updateConfig.Bot.GetUpdatesChan(u)
+	snapshot := s.collector.CollectSince(ctx, since)
+	if ctx.Err() != nil {
+		log.Printf("Rank command aborted: %v", ctx.Err())
+		return
+	}
 
-	for update := range updates {
-		if update.Message == nil {
-			continue
-		}
+	messageText := s.formatter.FormatRankWindowMessage(since, snapshot)
+	s.sendMessage(ctx, message.Chat.ID, messageText)
 
-		// Handle commands
-		if update.Message.IsCommand() {
-			switch update.Message.Command() {
-			case "rank":
-				sendRankCommand(updateConfig.Bot, updateConfig.Config, update.Message.Chat.ID)
-			case "help":
-				msg := tgbotapi.NewMessage(update.Message.Chat.ID, `Available commands:
-/rank - Show current rankings
-/help - Show this help message`)
-				if updateConfig.Config.Telegram.MessageThread != 0 {
-					msg.ReplyToMessageID = updateConfig.Config.Telegram.MessageThread
-				}
-				updateConfig.Bot.Send(msg)
-			}
-		}
+	// Record the fetched state without moving the /rank baseline of the chat
+	s.collector.Save(ctx, snapshot)
+}
+
+// SendRankChangeNotification sends a notification about rank changes
+func (s *TelegramService) SendRankChangeNotification(ctx context.Context, changes map[string]models.RankChangeInfo, users []models.UserRankInfo) {
+	// Format message
+	messageText := s.formatter.FormatRankChangeMessage(changes, users)
+
+	s.sendAlert(ctx, messageText)
+}
+
+// sendAlert sends an HTML formatted message to the configured alert chat
+func (s *TelegramService) sendAlert(ctx context.Context, text string) {
+	// Convert chat ID from string to int64
+	chatID, err := strconv.ParseInt(s.config.Telegram.ChatID, 10, 64)
+	if err != nil {
+		log.Printf("Error parsing chat ID: %v", err)
+		return
 	}
+
+	s.sendMessage(ctx, chatID, text)
 }
 
-// sendRankCommand handles the /rank command
-func sendRankCommand(bot *tgbotapi.BotAPI, cfg *config.Config, chatID int64) {
-	// Create required services
-	alloraService := NewAlloraService(cfg.Allora.API)
-	historyService := NewHistoryService("history")
-	formatter := utils.NewFormatter()
-
-	var users []models.UserRankInfo
-	changes := make(map[string]models.RankChangeInfo)
-
-	// Fetch and process data for each address
-	for _, address := range cfg.Allora.Address {
-		userData, err := alloraService.FetchUserData(address)
-		if err != nil {
-			log.Printf("Error fetching user data for %s: %v", address, err)
-			continue
-		}
+// CheckRankChanges checks for rank changes and sends notifications
+func (s *TelegramService) CheckRankChanges(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Checker.Timeout)
+	defer cancel()
 
-		// Get weight information for each competition
-		for i := range userData.Competitions {
-			comp := &userData.Competitions[i]
-			topicID := strconv.Itoa(comp.TopicID)
+	log.Println("Starting rank change check...")
+	// The value analysis reads the topic inferences the collector ranked the weights with
+	ctx = s.alloraService.WithInferenceCycle(ctx)
+	baseline := alertBaseline(s.config.Telegram.ChatID)
+	snapshot := s.collector.Collect(ctx, baseline)
+	if ctx.Err() != nil {
+		log.Printf("Rank change check aborted: %v", ctx.Err())
+		return
+	}
 
-			// Fetch network inferences for weight information
-			networkInferences, err := alloraService.FetchNetworkInferences(topicID)
-			if err != nil {
-				log.Printf("Error fetching network inferences for topic %s: %v", topicID, err)
-				continue
-			}
+	// Record every check so history holds weight, points and score changes between alerts
+	s.collector.Save(ctx, snapshot)
 
-			// Calculate weight rankings
-			var weights []models.WeightRank
-			for _, w := range networkInferences.InfererWeights {
-				weight, _ := strconv.ParseFloat(w.Weight, 64)
-				weights = append(weights, models.WeightRank{
-					Worker: w.Worker,
-					Weight: weight,
-				})
-			}
+	if alerts := s.eviction.Observe(ctx, snapshot); len(alerts) > 0 {
+		s.sendAlert(ctx, s.formatter.FormatEvictionAlerts(alerts))
+	}
+	if alerts := s.values.Observe(ctx, snapshot); len(alerts) > 0 {
+		s.sendAlert(ctx, s.formatter.FormatValueAlerts(alerts))
+	}
+	if alerts := s.presence.Observe(snapshot); len(alerts) > 0 {
+		s.sendAlert(ctx, s.formatter.FormatPresenceAlerts(alerts))
+	}
 
-			// Sort weights and assign ranks
-			sort.Slice(weights, func(i, j int) bool {
-				return weights[i].Weight > weights[j].Weight
-			})
-			for i := range weights {
-				weights[i].Rank = i + 1
-			}
+	// Notify and move the alert baseline only if there are rank changes
+	if hasRankChanges(snapshot.Changes) {
+		s.SendRankChangeNotification(ctx, snapshot.Changes, snapshot.Users)
+		s.collector.SaveBaseline(ctx, baseline, snapshot)
+	}
+}
 
-			// Find user's weight rank
-			for _, w := range weights {
-				if w.Worker == address {
-					comp.Weight = w.Weight
-					comp.WeightRank = w.Rank
-					comp.TotalWeightParticipants = len(weights)
-					break
-				}
-			}
-		}
+// alertBaseline names the baseline of the rank change alerts sent to a chat
+func alertBaseline(chatID string) string {
+	return "alert:" + chatID
+}
 
-		// Load previous history
-		prevHistory, err := historyService.LoadHistory(address)
-		if err != nil {
-			log.Printf("Error loading history for %s: %v", address, err)
-		}
+// rankBaseline names the baseline of the /rank command in a chat
+func rankBaseline(chatID int64) string {
+	return fmt.Sprintf("rank:%d", chatID)
+}
 
-		// Save current data as history
-		if err := historyService.SaveHistory(address, userData); err != nil {
-			log.Printf("Error saving history for %s: %v", address, err)
-		}
+// sendPhoto sends an image with an HTML formatted caption unless ctx is already done
+func (s *TelegramService) sendPhoto(ctx context.Context, chatID int64, file tgbotapi.RequestFileData, caption string) {
+	if err := ctx.Err(); err != nil {
+		log.Printf("Not sending photo to %d: %v", chatID, err)
+		return
+	}
 
-		// Create user rank info
-		userInfo := models.UserRankInfo{
-			Name:         fmt.Sprintf("%s %s", userData.FirstName, userData.LastName),
-			Username:     userData.Username,
-			Ranking:      userData.Ranking,
-			Points:       userData.TotalPoints,
-			BadgeName:    userData.BadgeName,
-			Address:      address,
-			Competitions: userData.Competitions,
-		}
-		users = append(users, userInfo)
-
-		// Calculate changes if previous history exists
-		if prevHistory != nil {
-			change := models.RankChangeInfo{
-				OverallRankChanged: prevHistory.Ranking != userData.Ranking,
-				OverallRankDiff:    prevHistory.Ranking - userData.Ranking,
-				PointsDiff:         userData.TotalPoints - prevHistory.TotalPoints,
-				CompChanges:        make(map[int]models.CompChangeInfo),
-			}
+	photo := tgbotapi.NewPhoto(chatID, file)
+	photo.Caption = caption
+	photo.ParseMode = tgbotapi.ModeHTML
+	if s.config.Telegram.MessageThread != 0 {
+		photo.ReplyToMessageID = s.config.Telegram.MessageThread
+	}
 
-			// Calculate competition changes
-			for _, comp := range userData.Competitions {
-				for _, prevComp := range prevHistory.Competitions {
-					if comp.ID == prevComp.ID {
-						change.CompChanges[comp.ID] = models.CompChangeInfo{
-							RankChanged:    prevComp.Ranking != comp.Ranking,
-							RankDiff:       prevComp.Ranking - comp.Ranking,
-							PointsDiff:     comp.Points - prevComp.Points,
-							WeightDiff:     comp.Weight - prevComp.Weight,
-							WeightRankDiff: prevComp.WeightRank - comp.WeightRank,
-						}
-						break
-					}
-				}
-			}
-			changes[address] = change
-		}
+	if _, err := s.bot.Send(photo); err != nil {
+		log.Printf("Error sending photo: %v", err)
 	}
+}
 
-	// Sort users by ranking
-	sort.Slice(users, func(i, j int) bool {
-		return users[i].Ranking < users[j].Ranking
-	})
+// sendDocument sends a file with an HTML formatted caption unless ctx is already done
+func (s *TelegramService) sendDocument(ctx context.Context, chatID int64, file tgbotapi.RequestFileData, caption string) {
+	if err := ctx.Err(); err != nil {
+		log.Printf("Not sending document to %d: %v", chatID, err)
+		return
+	}
 
-	// Format and send message
-	messageText := formatter.FormatRankChangeMessage(changes, users)
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	if cfg.Telegram.MessageThread != 0 {
-		msg.ReplyToMessageID = cfg.Telegram.MessageThread
+	document := tgbotapi.NewDocument(chatID, file)
+	document.Caption = caption
+	document.ParseMode = tgbotapi.ModeHTML
+	if s.config.Telegram.MessageThread != 0 {
+		document.ReplyToMessageID = s.config.Telegram.MessageThread
 	}
 
-	if _, err := bot.Send(msg); err != nil {
+	if _, err := s.bot.Send(document); err != nil {
+		log.Printf("Error sending document: %v", err)
+	}
+}
+
+// sendMessage sends an HTML formatted message to the chat unless ctx is already done
+func (s *TelegramService) sendMessage(ctx context.Context, chatID int64, text string) {
+	if err := ctx.Err(); err != nil {
+		log.Printf("Not sending message to %d: %v", chatID, err)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if s.config.Telegram.MessageThread != 0 {
+		msg.ReplyToMessageID = s.config.Telegram.MessageThread
+	}
+
+	if _, err := s.bot.Send(msg); err != nil {
 		log.Printf("Error sending message: %v", err)
 	}
 }
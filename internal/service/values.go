@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/config"
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// AnalyzeValue compares the value a worker submitted with the topic's combined inference.
+// A value is an outlier when the robust z-score of its deviation exceeds zThreshold.
+func (t *TopicInferences) AnalyzeValue(worker string, zThreshold float64) (*models.ValueAnalysis, bool) {
+	bundle := t.Response.NetworkInferences
+	combined, err := strconv.ParseFloat(bundle.CombinedValue, 64)
+	if err != nil {
+		return nil, false
+	}
+	naive, _ := strconv.ParseFloat(bundle.NaiveValue, 64)
+
+	var (
+		deviations []float64
+		own        float64
+		ownValue   float64
+		found      bool
+	)
+	for _, v := range bundle.InfererValues {
+		value, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			continue
+		}
+		deviation := value - combined
+		deviations = append(deviations, deviation)
+		if v.Worker == worker {
+			own, ownValue, found = deviation, value, true
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Share of inferers further away from the combined value
+	further := 0
+	for _, d := range deviations {
+		if math.Abs(d) > math.Abs(own) {
+			further++
+		}
+	}
+
+	analysis := &models.ValueAnalysis{
+		Worker:        worker,
+		Value:         ownValue,
+		CombinedValue: combined,
+		NaiveValue:    naive,
+		Deviation:     own,
+		Percentile:    float64(further) / float64(len(deviations)) * 100,
+	}
+	if combined != 0 {
+		analysis.RelativeDeviation = own / math.Abs(combined)
+	}
+
+	// Robust z-score based on the median absolute deviation of all inferers
+	median := medianOf(deviations)
+	absDev := make([]float64, len(deviations))
+	for i, d := range deviations {
+		absDev[i] = math.Abs(d - median)
+	}
+	if mad := medianOf(absDev); mad > 0 {
+		analysis.ZScore = (own - median) / (1.4826 * mad)
+		analysis.Outlier = math.Abs(analysis.ZScore) > zThreshold
+	}
+
+	return analysis, true
+}
+
+// medianOf returns the median of values without modifying them
+func medianOf(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// valueKey identifies the submitted values of an address in a topic
+type valueKey struct {
+	address string
+	topicID int
+}
+
+// ValueMonitor alerts when a tracked worker's submitted value becomes an outlier
+type ValueMonitor struct {
+	alloraService *AlloraService
+	zThreshold    float64
+	mu            sync.Mutex
+	outliers      map[valueKey]bool
+}
+
+// NewValueMonitor creates a new instance of ValueMonitor
+func NewValueMonitor(alloraService *AlloraService, cfg *config.Config) *ValueMonitor {
+	return &ValueMonitor{
+		alloraService: alloraService,
+		zThreshold:    cfg.Alerts.OutlierZScore,
+		outliers:      make(map[valueKey]bool),
+	}
+}
+
+// Observe analyzes the submitted values of a snapshot and returns alerts for outlier transitions
+func (m *ValueMonitor) Observe(ctx context.Context, snapshot *models.RankSnapshot) []models.ValueAlert {
+	var alerts []models.ValueAlert
+
+	for _, user := range snapshot.Users {
+		for _, comp := range user.Competitions {
+			inferences, err := m.alloraService.TopicInferences(ctx, strconv.Itoa(comp.TopicID))
+			if err != nil {
+				continue
+			}
+			analysis, ok := inferences.AnalyzeValue(user.Address, m.zThreshold)
+			if !ok {
+				continue
+			}
+
+			key := valueKey{address: user.Address, topicID: comp.TopicID}
+			m.mu.Lock()
+			wasOutlier := m.outliers[key]
+			m.outliers[key] = analysis.Outlier
+			m.mu.Unlock()
+
+			if analysis.Outlier == wasOutlier {
+				continue
+			}
+			alerts = append(alerts, models.ValueAlert{
+				Address:   user.Address,
+				Name:      user.Name,
+				CompID:    comp.ID,
+				CompName:  comp.Name,
+				TopicID:   comp.TopicID,
+				Analysis:  *analysis,
+				Recovered: !analysis.Outlier,
+			})
+		}
+	}
+
+	return alerts
+}
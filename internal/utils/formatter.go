@@ -2,12 +2,17 @@ package utils
 
 import (
 	"fmt"
+	"html"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/models"
 )
 
+// timestampLayout is how history timestamps are shown in messages
+const timestampLayout = "2006-01-02 15:04"
+
 type Formatter struct{}
 
 func NewFormatter() *Formatter {
@@ -68,11 +73,11 @@ func (f *Formatter) FormatRankChangeMessage(changes map[string]models.RankChange
 			rankChange := f.formatChange(float64(change.OverallRankDiff), "")
 			pointsChange := f.formatChange(change.PointsDiff, "%.2f")
 
-			sb.WriteString(fmt.Sprintf("%d. %s (@%s)\n", i+1, user.Name, user.Username))
+			sb.WriteString(fmt.Sprintf("%d. %s (@%s)\n", i+1, html.EscapeString(user.Name), html.EscapeString(user.Username)))
 			sb.WriteString(fmt.Sprintf("└ #%-3d%-8s | %-6.2f%-8s | 🏅 %s\n",
 				user.Ranking, rankChange,
 				user.Points, pointsChange,
-				user.BadgeName))
+				html.EscapeString(user.BadgeName)))
 		}
 	}
 
@@ -87,7 +92,7 @@ func (f *Formatter) FormatRankChangeMessage(changes map[string]models.RankChange
 	// 정렬된 ID 순서대로 경쟁 부문별 순위 작성
 	for _, compID := range compIDs {
 		name := compMap[compID]
-		sb.WriteString(fmt.Sprintf("\n🎯 [%d] %s\n", compID, name))
+		sb.WriteString(fmt.Sprintf("\n🎯 [%d] %s\n", compID, html.EscapeString(name)))
 		sb.WriteString("─────────────\n")
 
 		// Create temporary slice for sorting users by competition points
@@ -120,13 +125,57 @@ func (f *Formatter) FormatRankChangeMessage(changes map[string]models.RankChange
 					rankChange := f.formatChange(float64(compChange.RankDiff), "")
 					pointsChange := f.formatChange(compChange.PointsDiff, "%.2f")
 
-					sb.WriteString(fmt.Sprintf("%d. %s (@%s)\n", i+1, uc.user.Name, uc.user.Username))
+					sb.WriteString(fmt.Sprintf("%d. %s (@%s)\n", i+1, html.EscapeString(uc.user.Name), html.EscapeString(uc.user.Username)))
 					sb.WriteString(fmt.Sprintf("     #%-3d%-8s | %-6.2f%-8s | #%d/%d %.5f\n",
 						uc.comp.Ranking, rankChange,
 						uc.comp.Points, pointsChange,
 						uc.comp.WeightRank, uc.comp.TotalWeightParticipants,
 						uc.comp.Weight))
+					if roles := f.formatRoles(uc.comp.Roles); roles != "" {
+						sb.WriteString(fmt.Sprintf("     %s\n", roles))
+					}
+				}
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// FormatStatusMessage formats active set status, scores and margins per topic for each address
+func (f *Formatter) FormatStatusMessage(addresses []string, users []*models.AlloraUser) string {
+	var sb strings.Builder
+
+	sb.WriteString("🟢 Active Status\n")
+	sb.WriteString("─────────────\n")
+	for i, user := range users {
+		if user == nil {
+			sb.WriteString(fmt.Sprintf("\n%s\n└ failed to fetch data\n", html.EscapeString(addresses[i])))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("\n%s %s (@%s)\n",
+			html.EscapeString(user.FirstName), html.EscapeString(user.LastName), html.EscapeString(user.Username)))
+
+		comps := make([]models.Competition, len(user.Competitions))
+		copy(comps, user.Competitions)
+		sort.Slice(comps, func(i, j int) bool {
+			return comps[i].ID < comps[j].ID
+		})
+
+		for _, comp := range comps {
+			sb.WriteString(fmt.Sprintf("🎯 [%d] %s (topic %d)\n", comp.ID, html.EscapeString(comp.Name), comp.TopicID))
+			if len(comp.Roles) == 0 {
+				sb.WriteString("└ no score data\n")
+				continue
+			}
+			for _, role := range comp.Roles {
+				status := "❌ inactive"
+				if role.Active {
+					status = "✅ active"
 				}
+				sb.WriteString(fmt.Sprintf("└ %s %s | score %.5f | lowest %.5f | margin %+.5f | block %s\n",
+					role.Role, status, role.Score, role.LowestScore, role.Margin, role.BlockHeight))
 			}
 		}
 	}
@@ -134,6 +183,186 @@ func (f *Formatter) FormatRankChangeMessage(changes map[string]models.RankChange
 	return sb.String()
 }
 
+// FormatEvictionAlerts formats workers approaching or leaving the active set
+func (f *Formatter) FormatEvictionAlerts(alerts []models.EvictionAlert) string {
+	var sb strings.Builder
+
+	sb.WriteString("🚨 Active Set Alerts\n")
+	sb.WriteString("─────────────\n")
+	for _, alert := range alerts {
+		icon := "⚠️"
+		switch alert.Kind {
+		case models.EvictionEvicted:
+			icon = "❌"
+		case models.EvictionRecovered:
+			icon = "✅"
+		}
+
+		sb.WriteString(fmt.Sprintf("%s %s | [%d] %s | %s\n",
+			icon, html.EscapeString(alert.Name), alert.CompID, html.EscapeString(alert.CompName), alert.Role))
+		sb.WriteString(fmt.Sprintf("└ margin %+.5f, %s\n", alert.Margin, alert.Reason))
+	}
+
+	return sb.String()
+}
+
+// FormatTopicLeaderboard formats one page of a topic's workers ranked by weight.
+// Workers in labels are highlighted and summarized relative to the median weight.
+func (f *Formatter) FormatTopicLeaderboard(topicID int, weights []models.WeightRank, page, pageSize int, labels map[string]string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("🏆 Topic %d Weight Leaderboard\n", topicID))
+	sb.WriteString("─────────────\n")
+	sb.WriteString(fmt.Sprintf("Participants: %d\n", len(weights)))
+	if len(weights) == 0 {
+		return sb.String()
+	}
+
+	// Weights are sorted in descending order, so the median sits in the middle
+	median := weights[len(weights)/2].Weight
+	if len(weights)%2 == 0 {
+		median = (weights[len(weights)/2-1].Weight + weights[len(weights)/2].Weight) / 2
+	}
+	sb.WriteString(fmt.Sprintf("Median weight: %.5f\n", median))
+
+	// Our workers relative to the median
+	var ours []string
+	for _, w := range weights {
+		label, ok := labels[w.Worker]
+		if !ok {
+			continue
+		}
+		position := "above"
+		if w.Weight < median {
+			position = "below"
+		}
+		ours = append(ours, fmt.Sprintf("📍 %s #%d/%d %.5f (%s median)",
+			html.EscapeString(label), w.Rank, len(weights), w.Weight, position))
+	}
+	if len(ours) > 0 {
+		sb.WriteString("\n" + strings.Join(ours, "\n") + "\n")
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	if end > len(weights) {
+		end = len(weights)
+	}
+
+	sb.WriteString("\n")
+	for _, w := range weights[start:end] {
+		if label, ok := labels[w.Worker]; ok {
+			sb.WriteString(fmt.Sprintf("<b>#%-3d %.5f %s</b> ⭐\n", w.Rank, w.Weight, html.EscapeString(label)))
+			continue
+		}
+		worker := w.Worker
+		if len(worker) > 16 {
+			worker = worker[:10] + "…" + worker[len(worker)-4:]
+		}
+		sb.WriteString(fmt.Sprintf("#%-3d %.5f %s\n", w.Rank, w.Weight, html.EscapeString(worker)))
+	}
+
+	return sb.String()
+}
+
+// FormatValueAnalysis formats our workers' submitted values against the combined inference
+func (f *Formatter) FormatValueAnalysis(analyses []models.ValueAnalysis, labels map[string]string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("📐 Combined %.6f | Naive %.6f\n", analyses[0].CombinedValue, analyses[0].NaiveValue))
+	for _, a := range analyses {
+		flag := ""
+		if a.Outlier {
+			flag = " 🚩"
+		}
+		sb.WriteString(fmt.Sprintf("%s %.6f | dev %+.6f (%+.2f%%) | p%.0f%s\n",
+			html.EscapeString(labels[a.Worker]), a.Value, a.Deviation, a.RelativeDeviation*100, a.Percentile, flag))
+	}
+
+	return sb.String()
+}
+
+// FormatValueAlerts formats workers whose submitted value became or stopped being an outlier
+func (f *Formatter) FormatValueAlerts(alerts []models.ValueAlert) string {
+	var sb strings.Builder
+
+	sb.WriteString("🚩 Inference Value Alerts\n")
+	sb.WriteString("─────────────\n")
+	for _, alert := range alerts {
+		status := "🚩 outlier, check the model"
+		if alert.Recovered {
+			status = "✅ back in line"
+		}
+		a := alert.Analysis
+		sb.WriteString(fmt.Sprintf("%s | [%d] %s | %s\n",
+			html.EscapeString(alert.Name), alert.CompID, html.EscapeString(alert.CompName), status))
+		sb.WriteString(fmt.Sprintf("└ value %.6f | combined %.6f | dev %+.6f (%+.2f%%) | z %.1f\n",
+			a.Value, a.CombinedValue, a.Deviation, a.RelativeDeviation*100, a.ZScore))
+	}
+
+	return sb.String()
+}
+
+// FormatPresenceAlerts formats workers missing from or back in a topic's inferer weights
+func (f *Formatter) FormatPresenceAlerts(alerts []models.PresenceAlert) string {
+	var sb strings.Builder
+
+	sb.WriteString("📡 Worker Presence Alerts\n")
+	sb.WriteString("─────────────\n")
+	for _, alert := range alerts {
+		if alert.Recovered {
+			sb.WriteString(fmt.Sprintf("✅ %s | [%d] %s (topic %d)\n└ back in the latest network inferences after %d missed checks\n",
+				html.EscapeString(alert.Name), alert.CompID, html.EscapeString(alert.CompName), alert.TopicID, alert.MissingChecks))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("⚠️ %s | [%d] %s (topic %d)\n└ missing from the latest network inferences for %d checks, is the worker still submitting?\n",
+			html.EscapeString(alert.Name), alert.CompID, html.EscapeString(alert.CompName), alert.TopicID, alert.MissingChecks))
+	}
+
+	return sb.String()
+}
+
+// FormatRankWindowMessage formats rank changes against the history nearest to since,
+// with a header showing which timestamps were compared
+func (f *Formatter) FormatRankWindowMessage(since time.Time, snapshot *models.RankSnapshot) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("🕒 Changes since %s\n", since.Format(timestampLayout)))
+	sb.WriteString("─────────────\n")
+
+	var earliest, latest time.Time
+	var missing []string
+	for _, user := range snapshot.Users {
+		baseline, ok := snapshot.Baselines[user.Address]
+		if !ok {
+			missing = append(missing, html.EscapeString(user.Name))
+			continue
+		}
+		if earliest.IsZero() || baseline.Before(earliest) {
+			earliest = baseline
+		}
+		if baseline.After(latest) {
+			latest = baseline
+		}
+	}
+
+	switch {
+	case earliest.IsZero():
+		sb.WriteString("No stored history to compare against\n")
+	case earliest.Equal(latest):
+		sb.WriteString(fmt.Sprintf("Compared %s → %s\n", earliest.Format(timestampLayout), snapshot.Timestamp.Format(timestampLayout)))
+	default:
+		sb.WriteString(fmt.Sprintf("Compared %s ~ %s → %s\n", earliest.Format(timestampLayout), latest.Format(timestampLayout), snapshot.Timestamp.Format(timestampLayout)))
+	}
+	if len(missing) > 0 && !earliest.IsZero() {
+		sb.WriteString(fmt.Sprintf("No stored history for %s\n", strings.Join(missing, ", ")))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString(f.FormatRankChangeMessage(snapshot.Changes, snapshot.Users))
+	return sb.String()
+}
+
 // Helper methods
 func (f *Formatter) writeHeader(sb *strings.Builder, title string) {
 	sb.WriteString(title + "\n")
@@ -173,6 +402,19 @@ func (f *Formatter) writeCompetitionDetails(sb *strings.Builder, comp models.Com
 		comp.Weight))
 }
 
+// formatRoles formats active set status and score margin for each role
+func (f *Formatter) formatRoles(roles []models.RoleStatus) string {
+	parts := make([]string, 0, len(roles))
+	for _, role := range roles {
+		icon := "❌"
+		if role.Active {
+			icon = "✅"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %+.5f", icon, role.Role, role.Margin))
+	}
+	return strings.Join(parts, " | ")
+}
+
 func (f *Formatter) formatChange(diff float64, format string) string {
 	if diff == 0 {
 		return "   " // 변화 없을 때 공백으로 처리
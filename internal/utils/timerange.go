@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date layouts accepted after "since", interpreted in local time
+var sinceLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	time.RFC3339,
+}
+
+// ParseSince parses a time window such as "1h", "24h", "7d", "2w" or "since 2026-10-01"
+// and returns the start of the window relative to now
+func ParseSince(arg string, now time.Time) (time.Time, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return time.Time{}, fmt.Errorf("empty time window")
+	}
+
+	if rest, ok := strings.CutPrefix(arg, "since"); ok {
+		rest = strings.TrimSpace(rest)
+		for _, layout := range sinceLayouts {
+			if t, err := time.ParseInLocation(layout, rest, now.Location()); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", rest)
+	}
+
+	d, err := parseWindow(arg)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
+
+// parseWindow parses a duration that may also use d for days and w for weeks
+func parseWindow(arg string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[arg[len(arg)-1]]; ok {
+		n, err := strconv.Atoi(arg[:len(arg)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid time window %q", arg)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(arg)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time window %q", arg)
+	}
+	return d, nil
+}
@@ -1,27 +1,57 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dntjd1097/allora-checker-bot/internal/models"
 )
 
-const version = "v8"
+// Client is the interface used to query the Allora forge and emissions APIs
+type Client interface {
+	FetchUserData(ctx context.Context, address string) (*models.AlloraUser, error)
+	FetchScore(ctx context.Context, role models.Role, topicID, address string) (*models.ScoreData, error)
+	FetchLowestScore(ctx context.Context, role models.Role, topicID string) (*models.ScoreData, error)
+	FetchNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error)
+	TopicFetcher
+}
+
+// TopicFetcher is implemented by clients that can query topic epoch information
+type TopicFetcher interface {
+	FetchTopic(ctx context.Context, topicID string) (*models.Topic, error)
+}
 
 // AlloraClient handles communication with the Allora API
 type AlloraClient struct {
 	httpClient *http.Client
 	baseURL    string
 	apiURL     string
+	retry      RetryPolicy
+	versions   []string
+	mu         sync.Mutex
+	version    string
+	// probe is the version detection in flight, probeFailed the time the last one failed
+	probe       *versionProbe
+	probeFailed time.Time
 }
 
-// NewAlloraClient creates a new instance of AlloraClient
-func NewAlloraClient(baseURL, apiURL string) *AlloraClient {
+var _ Client = (*AlloraClient)(nil)
+
+// NewAlloraClient creates a new instance of AlloraClient.
+// versions lists the emissions query versions to try, newest first.
+func NewAlloraClient(baseURL, apiURL string, retry RetryPolicy, versions []string) *AlloraClient {
+	if len(versions) == 0 {
+		versions = DefaultEmissionsVersions
+	}
+
 	return &AlloraClient{
 		httpClient: &http.Client{
 			Timeout: time.Second * 60,
@@ -31,98 +61,181 @@ func NewAlloraClient(baseURL, apiURL string) *AlloraClient {
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		baseURL: baseURL,
-		apiURL:  apiURL,
+		baseURL:  baseURL,
+		apiURL:   apiURL,
+		retry:    retry.withDefaults(),
+		versions: versions,
 	}
 }
 
-// GetUserData fetches user data from the Allora API
-func (c *AlloraClient) GetUserData(address string) (*models.AlloraUser, error) {
-	url := fmt.Sprintf("%s/api/upshot-api-proxy/allora/forge/user/%s", c.baseURL, address)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user data: %w", err)
-	}
-	defer resp.Body.Close()
+// request identifies a single call to an Allora endpoint
+type request struct {
+	endpoint string
+	url      string
+	topicID  string
+	address  string
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// FetchUserData fetches user data from the Allora API
+func (c *AlloraClient) FetchUserData(ctx context.Context, address string) (*models.AlloraUser, error) {
+	req := request{
+		endpoint: "user",
+		url:      fmt.Sprintf("%s/api/upshot-api-proxy/allora/forge/user/%s", c.baseURL, address),
+		address:  address,
 	}
 
 	var response models.AlloraResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := c.getJSON(ctx, req, &response); err != nil {
+		return nil, err
+	}
+	if !response.Status {
+		return nil, &APIError{Kind: ErrRequestFailed, Endpoint: req.endpoint, Address: address}
 	}
 
 	return &response.Data, nil
 }
 
-// GetScore fetches score for a specific topic and address
-func (c *AlloraClient) GetScore(topicID, address string) (*models.ScoreData, error) {
-	url := fmt.Sprintf("%s/emissions/%s/inferer_score_ema/%s/%s", c.apiURL, version, topicID, address)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch score: %w", err)
+// FetchScore fetches the score EMA of a role for a specific topic and address
+func (c *AlloraClient) FetchScore(ctx context.Context, role models.Role, topicID, address string) (*models.ScoreData, error) {
+	endpoint := fmt.Sprintf("%s_score_ema", role)
+	req := request{
+		endpoint: endpoint,
+		url:      fmt.Sprintf("%s/emissions/%s/%s/%s/%s", c.apiURL, c.emissionsVersion(ctx), endpoint, topicID, address),
+		topicID:  topicID,
+		address:  address,
 	}
-	defer resp.Body.Close()
 
 	var scoreResp models.ScoreResponse
-	if err := json.NewDecoder(resp.Body).Decode(&scoreResp); err != nil {
-		return nil, fmt.Errorf("failed to decode score response: %w", err)
+	if err := c.getJSON(ctx, req, &scoreResp); err != nil {
+		c.checkVersion(err)
+		return nil, err
 	}
 
 	return &scoreResp.Score, nil
 }
 
-// GetLowestScore fetches the lowest score for a specific topic
-func (c *AlloraClient) GetLowestScore(topicID string) (*models.ScoreData, error) {
-	url := fmt.Sprintf("%s/emissions/%s/current_lowest_inferer_score/%s", c.apiURL, version, topicID)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch lowest score: %w", err)
+// FetchLowestScore fetches the lowest score of a role for a specific topic
+func (c *AlloraClient) FetchLowestScore(ctx context.Context, role models.Role, topicID string) (*models.ScoreData, error) {
+	endpoint := fmt.Sprintf("current_lowest_%s_score", role)
+	req := request{
+		endpoint: endpoint,
+		url:      fmt.Sprintf("%s/emissions/%s/%s/%s", c.apiURL, c.emissionsVersion(ctx), endpoint, topicID),
+		topicID:  topicID,
 	}
-	defer resp.Body.Close()
 
 	var scoreResp models.ScoreResponse
-	if err := json.NewDecoder(resp.Body).Decode(&scoreResp); err != nil {
-		return nil, fmt.Errorf("failed to decode lowest score response: %w", err)
+	if err := c.getJSON(ctx, req, &scoreResp); err != nil {
+		c.checkVersion(err)
+		return nil, err
 	}
 
 	return &scoreResp.Score, nil
 }
 
-// GetNetworkInferences fetches network inferences for a specific topic
-func (c *AlloraClient) GetNetworkInferences(topicID string) (*models.NetworkInferencesResponse, error) {
-	url := fmt.Sprintf("%s/emissions/%s/latest_network_inferences/%s", c.apiURL, version, topicID)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch network inferences: %w", err)
+// FetchNetworkInferences fetches network inferences for a specific topic
+func (c *AlloraClient) FetchNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error) {
+	req := request{
+		endpoint: "latest_network_inferences",
+		url:      fmt.Sprintf("%s/emissions/%s/latest_network_inferences/%s", c.apiURL, c.emissionsVersion(ctx), topicID),
+		topicID:  topicID,
 	}
-	defer resp.Body.Close()
 
 	var result models.NetworkInferencesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode network inferences response: %w", err)
+	if err := c.getJSON(ctx, req, &result); err != nil {
+		c.checkVersion(err)
+		return nil, err
 	}
 
 	return &result, nil
 }
 
-// IsActive checks if a user is active in a specific competition
-func (c *AlloraClient) IsActive(topicID, address string) (bool, float64, error) {
-	userScore, err := c.GetScore(topicID, address)
+// FetchTopic fetches epoch information for a specific topic
+func (c *AlloraClient) FetchTopic(ctx context.Context, topicID string) (*models.Topic, error) {
+	req := request{
+		endpoint: "topics",
+		url:      fmt.Sprintf("%s/emissions/%s/topics/%s", c.apiURL, c.emissionsVersion(ctx), topicID),
+		topicID:  topicID,
+	}
+
+	var result models.TopicResponse
+	if err := c.getJSON(ctx, req, &result); err != nil {
+		c.checkVersion(err)
+		return nil, err
+	}
+
+	return &result.Topic, nil
+}
+
+// getJSON performs a GET request with retries and decodes a successful JSON response into v.
+// The retry deadline is applied on top of any deadline already set on ctx.
+func (c *AlloraClient) getJSON(ctx context.Context, req request, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.retry.Deadline)
+	defer cancel()
+
+	var err error
+	for attempt := 1; attempt <= c.retry.MaxAttempts; attempt++ {
+		err = c.doGetJSON(ctx, req, v)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Fetched %s after %d retries", req.endpoint, attempt-1)
+			}
+			return nil
+		}
+		if !isRetryable(err) || ctx.Err() != nil || attempt == c.retry.MaxAttempts {
+			break
+		}
+
+		delay := c.retry.retryDelay(attempt-1, err)
+		log.Printf("Retrying %s in %s (attempt %d/%d): %v", req.endpoint, delay.Round(time.Millisecond), attempt+1, c.retry.MaxAttempts, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s aborted after %d attempts (%v): %w", req.endpoint, attempt, ctx.Err(), err)
+		}
+	}
+
+	return err
+}
+
+// doGetJSON performs a single GET request and decodes the response into v
+func (c *AlloraClient) doGetJSON(ctx context.Context, req request, v interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.url, nil)
 	if err != nil {
-		return false, 0, err
+		return fmt.Errorf("failed to create %s request: %w", req.endpoint, err)
+	}
+	// Only chain queries can be served at a past height, the forge always returns current data
+	if height := HeightFrom(ctx); height > 0 && strings.HasPrefix(req.url, c.apiURL) {
+		httpReq.Header.Set(BlockHeightHeader, strconv.FormatInt(height, 10))
 	}
 
-	lowestScore, err := c.GetLowestScore(topicID)
+	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return false, 0, err
+		return fmt.Errorf("failed to fetch %s: %w", req.endpoint, err)
 	}
+	defer resp.Body.Close()
 
-	userScoreFloat, _ := strconv.ParseFloat(userScore.Score, 64)
-	lowestScoreFloat, _ := strconv.ParseFloat(lowestScore.Score, 64)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(resp, req)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response body: %w", req.endpoint, err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return &APIError{
+			Kind:       ErrSchemaMismatch,
+			Endpoint:   req.endpoint,
+			TopicID:    req.topicID,
+			Address:    req.address,
+			StatusCode: resp.StatusCode,
+			Err:        err,
+		}
+	}
 
-	return userScoreFloat > lowestScoreFloat, userScoreFloat - lowestScoreFloat, nil
+	return nil
 }
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// BlockSubscriber subscribes to NewBlock events on a CometBFT websocket
+type BlockSubscriber struct {
+	wsURL  string
+	dialer *websocket.Dialer
+}
+
+// NewBlockSubscriber creates a new instance of BlockSubscriber from an RPC endpoint
+func NewBlockSubscriber(rpcURL string) (*BlockSubscriber, error) {
+	wsURL, err := websocketURL(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlockSubscriber{
+		wsURL: wsURL,
+		dialer: &websocket.Dialer{
+			HandshakeTimeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// newBlockEvent is the JSON-RPC message delivered for every NewBlock event
+type newBlockEvent struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Result struct {
+		Data struct {
+			Value struct {
+				Block struct {
+					Header struct {
+						Height string `json:"height"`
+					} `json:"header"`
+				} `json:"block"`
+			} `json:"value"`
+		} `json:"data"`
+	} `json:"result"`
+}
+
+// Subscribe connects and calls onBlock with the height of every new block.
+// It blocks until ctx is cancelled or the connection drops, and always returns a non-nil error.
+func (s *BlockSubscriber) Subscribe(ctx context.Context, onBlock func(height int64)) error {
+	conn, _, err := s.dialer.DialContext(ctx, s.wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.wsURL, err)
+	}
+	defer conn.Close()
+
+	// Unblock ReadJSON when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	subscribe := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "subscribe",
+		"id":      1,
+		"params": map[string]string{
+			"query": "tm.event='NewBlock'",
+		},
+	}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+	}
+
+	for {
+		// Blocks are produced every few seconds, a long silence means the subscription is stale
+		conn.SetReadDeadline(time.Now().Add(2 * time.Minute))
+
+		var event newBlockEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("block subscription dropped: %w", err)
+		}
+		if event.Error != nil {
+			return fmt.Errorf("block subscription error %d: %s", event.Error.Code, event.Error.Message)
+		}
+
+		// The subscription confirmation carries no block
+		heightStr := event.Result.Data.Value.Block.Header.Height
+		if heightStr == "" {
+			continue
+		}
+		height, err := strconv.ParseInt(heightStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		onBlock(height)
+	}
+}
+
+// websocketURL converts an RPC endpoint such as https://rpc.example.com into its websocket URL
+func websocketURL(rpcURL string) (string, error) {
+	u, err := url.Parse(rpcURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid rpc url %q: %w", rpcURL, err)
+	}
+
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	case "http", "ws":
+		u.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("unsupported rpc url scheme %q", u.Scheme)
+	}
+	if !strings.HasSuffix(u.Path, "/websocket") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/websocket"
+	}
+	return u.String(), nil
+}
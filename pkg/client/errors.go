@@ -0,0 +1,104 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds returned by the Allora client, use errors.Is to check them
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrServer           = errors.New("server error")
+	ErrUnexpectedStatus = errors.New("unexpected status")
+	ErrSchemaMismatch   = errors.New("schema mismatch")
+	ErrRequestFailed    = errors.New("request reported failure")
+)
+
+// APIError describes a failed call to an Allora endpoint
+type APIError struct {
+	Kind       error
+	Endpoint   string
+	TopicID    string
+	Address    string
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %v", e.Endpoint, e.Kind))
+	if e.StatusCode != 0 {
+		sb.WriteString(fmt.Sprintf(" (status %d)", e.StatusCode))
+	}
+	if e.TopicID != "" {
+		sb.WriteString(fmt.Sprintf(" topic=%s", e.TopicID))
+	}
+	if e.Address != "" {
+		sb.WriteString(fmt.Sprintf(" address=%s", e.Address))
+	}
+	if e.RetryAfter > 0 {
+		sb.WriteString(fmt.Sprintf(" retry after %s", e.RetryAfter))
+	}
+	if e.Err != nil {
+		sb.WriteString(fmt.Sprintf(": %v", e.Err))
+	}
+	return sb.String()
+}
+
+// Is reports whether target matches the kind of the error
+func (e *APIError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// statusError converts a non-2xx response into an APIError
+func statusError(resp *http.Response, req request) *APIError {
+	apiErr := &APIError{
+		Endpoint:   req.endpoint,
+		TopicID:    req.topicID,
+		Address:    req.address,
+		StatusCode: resp.StatusCode,
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		apiErr.Kind = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.Kind = ErrRateLimited
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode == http.StatusNotImplemented:
+		// Unknown gateway routes are reported as not implemented and never succeed on retry
+		apiErr.Kind = ErrUnexpectedStatus
+	case resp.StatusCode >= 500:
+		apiErr.Kind = ErrServer
+	default:
+		apiErr.Kind = ErrUnexpectedStatus
+	}
+
+	return apiErr
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// FallbackClient serves chain queries from a ChainClient when the primary client is unavailable
+type FallbackClient struct {
+	Client
+	chain ChainClient
+}
+
+var (
+	_ Client          = (*FallbackClient)(nil)
+	_ VersionDetector = (*FallbackClient)(nil)
+)
+
+// NewFallbackClient creates a new instance of FallbackClient
+func NewFallbackClient(primary Client, chain ChainClient) *FallbackClient {
+	return &FallbackClient{
+		Client: primary,
+		chain:  chain,
+	}
+}
+
+// FetchScore fetches the score EMA of a role for a specific topic and address
+func (c *FallbackClient) FetchScore(ctx context.Context, role models.Role, topicID, address string) (*models.ScoreData, error) {
+	score, err := c.Client.FetchScore(ctx, role, topicID, address)
+	if !shouldFallback(ctx, err) {
+		return score, err
+	}
+	log.Printf("Falling back to RPC for %s_score_ema: %v", role, err)
+	return c.chain.FetchScore(ctx, role, topicID, address)
+}
+
+// FetchLowestScore fetches the lowest score of a role for a specific topic
+func (c *FallbackClient) FetchLowestScore(ctx context.Context, role models.Role, topicID string) (*models.ScoreData, error) {
+	score, err := c.Client.FetchLowestScore(ctx, role, topicID)
+	if !shouldFallback(ctx, err) {
+		return score, err
+	}
+	log.Printf("Falling back to RPC for current_lowest_%s_score: %v", role, err)
+	return c.chain.FetchLowestScore(ctx, role, topicID)
+}
+
+// FetchNetworkInferences fetches network inferences for a specific topic
+func (c *FallbackClient) FetchNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error) {
+	result, err := c.Client.FetchNetworkInferences(ctx, topicID)
+	if !shouldFallback(ctx, err) {
+		return result, err
+	}
+	log.Printf("Falling back to RPC for latest_network_inferences: %v", err)
+	return c.chain.FetchNetworkInferences(ctx, topicID)
+}
+
+// DetectVersion detects the emissions version of the primary client
+func (c *FallbackClient) DetectVersion(ctx context.Context) (string, error) {
+	detector, ok := c.Client.(VersionDetector)
+	if !ok {
+		return "", fmt.Errorf("primary client does not detect emissions versions")
+	}
+	return detector.DetectVersion(ctx)
+}
+
+// EmissionsVersion returns the emissions version detected by the primary client, or an empty string if unknown
+func (c *FallbackClient) EmissionsVersion() string {
+	if detector, ok := c.Client.(VersionDetector); ok {
+		return detector.EmissionsVersion()
+	}
+	return ""
+}
+
+// shouldFallback reports whether a failed request indicates the REST gateway is unavailable
+func shouldFallback(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, ErrNotFound)
+}
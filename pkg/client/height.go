@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// BlockHeightHeader selects the block height a Cosmos REST query reads chain state at
+const BlockHeightHeader = "x-cosmos-block-height"
+
+// heightKey is the context key of the query height
+type heightKey struct{}
+
+// WithHeight returns a context whose chain queries read state at a past block height.
+// The node must still hold that state, which usually requires an archive node.
+func WithHeight(ctx context.Context, height int64) context.Context {
+	return context.WithValue(ctx, heightKey{}, height)
+}
+
+// HeightFrom returns the query height set with WithHeight, or 0 for the latest state
+func HeightFrom(ctx context.Context) int64 {
+	height, _ := ctx.Value(heightKey{}).(int64)
+	return height
+}
+
+// BlockFetcher is implemented by clients that can query block headers
+type BlockFetcher interface {
+	// FetchBlock fetches the header of a block, or of the latest block if height is 0
+	FetchBlock(ctx context.Context, height int64) (*models.BlockHeader, error)
+}
+
+var _ BlockFetcher = (*AlloraClient)(nil)
+
+// FetchBlock fetches the header of a block, or of the latest block if height is 0
+func (c *AlloraClient) FetchBlock(ctx context.Context, height int64) (*models.BlockHeader, error) {
+	target := "latest"
+	if height > 0 {
+		target = strconv.FormatInt(height, 10)
+	}
+	req := request{
+		endpoint: "blocks",
+		url:      fmt.Sprintf("%s/cosmos/base/tendermint/v1beta1/blocks/%s", c.apiURL, target),
+	}
+
+	var result models.BlockResponse
+	if err := c.getJSON(ctx, req, &result); err != nil {
+		return nil, err
+	}
+
+	return &result.Block.Header, nil
+}
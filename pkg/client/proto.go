@@ -0,0 +1,172 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the emissions query messages decoded from abci_query responses
+const (
+	// QueryInfererScoreEmaResponse and QueryCurrentLowestInfererScoreResponse
+	fieldScoreResponseScore = 1
+
+	// Score
+	fieldScoreTopicID     = 1
+	fieldScoreBlockHeight = 2
+	fieldScoreAddress     = 3
+	fieldScoreValue       = 4
+
+	// QueryLatestNetworkInferencesResponse
+	fieldNetworkInferences = 1
+	fieldInfererWeights    = 2
+
+	// ValueBundle
+	fieldBundleTopicID       = 1
+	fieldBundleCombinedValue = 5
+	fieldBundleInfererValues = 6
+	fieldBundleNaiveValue    = 8
+
+	// WorkerAttributedValue and RegretInformedWeight
+	fieldWorker      = 1
+	fieldWorkerValue = 2
+)
+
+// appendUint64Field appends a varint field to a protobuf message
+func appendUint64Field(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendStringField appends a length-delimited string field to a protobuf message
+func appendStringField(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// rangeFields calls fn for every field of a protobuf message.
+// Varint fields are passed as v, length-delimited fields as data; other types are skipped.
+func rangeFields(b []byte, fn func(num protowire.Number, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			if err := fn(num, v, nil); err != nil {
+				return err
+			}
+		case protowire.BytesType:
+			data, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			if err := fn(num, 0, data); err != nil {
+				return err
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return nil
+}
+
+// decodeScoreResponse decodes a score query response into ScoreData
+func decodeScoreResponse(value []byte, req request) (*models.ScoreData, error) {
+	var score models.ScoreData
+	err := rangeFields(value, func(num protowire.Number, _ uint64, data []byte) error {
+		if num != fieldScoreResponseScore {
+			return nil
+		}
+		return rangeFields(data, func(num protowire.Number, v uint64, data []byte) error {
+			switch num {
+			case fieldScoreTopicID:
+				score.TopicID = strconv.FormatUint(v, 10)
+			case fieldScoreBlockHeight:
+				score.BlockHeight = strconv.FormatInt(int64(v), 10)
+			case fieldScoreAddress:
+				score.Address = string(data)
+			case fieldScoreValue:
+				score.Score = string(data)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, &APIError{Kind: ErrSchemaMismatch, Endpoint: req.endpoint, TopicID: req.topicID, Address: req.address, Err: err}
+	}
+	return &score, nil
+}
+
+// decodeNetworkInferencesResponse decodes a latest network inferences query response
+func decodeNetworkInferencesResponse(value []byte) (*models.NetworkInferencesResponse, error) {
+	var result models.NetworkInferencesResponse
+	err := rangeFields(value, func(num protowire.Number, _ uint64, data []byte) error {
+		switch num {
+		case fieldNetworkInferences:
+			return decodeValueBundle(data, &result)
+		case fieldInfererWeights:
+			worker, weight, err := decodeWorkerValue(data)
+			if err != nil {
+				return fmt.Errorf("failed to decode inferer weight: %w", err)
+			}
+			result.InfererWeights = append(result.InfererWeights, models.InfererWeight{Worker: worker, Weight: weight})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// decodeValueBundle decodes the network inferences value bundle
+func decodeValueBundle(b []byte, result *models.NetworkInferencesResponse) error {
+	return rangeFields(b, func(num protowire.Number, v uint64, data []byte) error {
+		switch num {
+		case fieldBundleTopicID:
+			result.NetworkInferences.TopicID = strconv.FormatUint(v, 10)
+		case fieldBundleCombinedValue:
+			result.NetworkInferences.CombinedValue = string(data)
+		case fieldBundleNaiveValue:
+			result.NetworkInferences.NaiveValue = string(data)
+		case fieldBundleInfererValues:
+			worker, value, err := decodeWorkerValue(data)
+			if err != nil {
+				return fmt.Errorf("failed to decode inferer value: %w", err)
+			}
+			result.NetworkInferences.InfererValues = append(result.NetworkInferences.InfererValues, models.InfererValue{Worker: worker, Value: value})
+		}
+		return nil
+	})
+}
+
+// decodeWorkerValue decodes a message holding a worker address and a decimal string
+func decodeWorkerValue(b []byte) (string, string, error) {
+	var worker, value string
+	err := rangeFields(b, func(num protowire.Number, _ uint64, data []byte) error {
+		switch num {
+		case fieldWorker:
+			worker = string(data)
+		case fieldWorkerValue:
+			value = string(data)
+		}
+		return nil
+	})
+	return worker, value, err
+}
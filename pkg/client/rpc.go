@@ -0,0 +1,281 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+)
+
+// ChainClient is the subset of Client that can be served directly by the chain
+type ChainClient interface {
+	FetchScore(ctx context.Context, role models.Role, topicID, address string) (*models.ScoreData, error)
+	FetchLowestScore(ctx context.Context, role models.Role, topicID string) (*models.ScoreData, error)
+	FetchNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error)
+}
+
+// RPCClient queries the emissions module through CometBFT JSON-RPC abci_query
+type RPCClient struct {
+	httpClient *http.Client
+	rpcURL     string
+	retry      RetryPolicy
+	versions   []string
+	mu         sync.Mutex
+	version    string
+}
+
+var _ ChainClient = (*RPCClient)(nil)
+
+// NewRPCClient creates a new instance of RPCClient.
+// versions lists the emissions query versions to try, newest first.
+func NewRPCClient(rpcURL string, retry RetryPolicy, versions []string) *RPCClient {
+	if len(versions) == 0 {
+		versions = DefaultEmissionsVersions
+	}
+
+	return &RPCClient{
+		httpClient: &http.Client{
+			Timeout: time.Second * 60,
+		},
+		rpcURL:   rpcURL,
+		retry:    retry.withDefaults(),
+		versions: versions,
+	}
+}
+
+// errUnknownQueryPath is returned when the chain does not serve a query path
+var errUnknownQueryPath = errors.New("unknown query path")
+
+// abciQueryResponse is the JSON-RPC envelope of an abci_query call
+type abciQueryResponse struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+	Result struct {
+		Response struct {
+			Code      uint32 `json:"code"`
+			Codespace string `json:"codespace"`
+			Log       string `json:"log"`
+			Value     []byte `json:"value"`
+			Height    string `json:"height"`
+		} `json:"response"`
+	} `json:"result"`
+}
+
+// FetchScore fetches the score EMA of a role for a specific topic and address
+func (c *RPCClient) FetchScore(ctx context.Context, role models.Role, topicID, address string) (*models.ScoreData, error) {
+	topic, err := strconv.ParseUint(topicID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic id %q: %w", topicID, err)
+	}
+
+	req := request{endpoint: fmt.Sprintf("%s_score_ema", role), topicID: topicID, address: address}
+	data := appendUint64Field(nil, 1, topic)
+	data = appendStringField(data, 2, address)
+
+	value, err := c.query(ctx, req, fmt.Sprintf("Get%sScoreEma", roleMethodName(role)), data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeScoreResponse(value, req)
+}
+
+// FetchLowestScore fetches the current lowest score of a role for a specific topic
+func (c *RPCClient) FetchLowestScore(ctx context.Context, role models.Role, topicID string) (*models.ScoreData, error) {
+	topic, err := strconv.ParseUint(topicID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic id %q: %w", topicID, err)
+	}
+
+	req := request{endpoint: fmt.Sprintf("current_lowest_%s_score", role), topicID: topicID}
+	value, err := c.query(ctx, req, fmt.Sprintf("GetCurrentLowest%sScore", roleMethodName(role)), appendUint64Field(nil, 1, topic))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeScoreResponse(value, req)
+}
+
+// FetchNetworkInferences fetches the latest network inferences for a specific topic
+func (c *RPCClient) FetchNetworkInferences(ctx context.Context, topicID string) (*models.NetworkInferencesResponse, error) {
+	topic, err := strconv.ParseUint(topicID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic id %q: %w", topicID, err)
+	}
+
+	req := request{endpoint: "latest_network_inferences", topicID: topicID}
+	value, err := c.query(ctx, req, "GetLatestNetworkInferences", appendUint64Field(nil, 1, topic))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := decodeNetworkInferencesResponse(value)
+	if err != nil {
+		return nil, &APIError{Kind: ErrSchemaMismatch, Endpoint: req.endpoint, TopicID: topicID, Err: err}
+	}
+	return result, nil
+}
+
+// roleMethodName returns the role as used in emissions query method names, e.g. Inferer
+func roleMethodName(role models.Role) string {
+	name := string(role)
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// query runs an emissions query, detecting the supported version on first use
+func (c *RPCClient) query(ctx context.Context, req request, method string, data []byte) ([]byte, error) {
+	c.mu.Lock()
+	version := c.version
+	c.mu.Unlock()
+
+	if version != "" {
+		value, err := c.abciQuery(ctx, req, fmt.Sprintf("/emissions.%s.QueryService/%s", version, method), data)
+		if !errors.Is(err, errUnknownQueryPath) {
+			return value, err
+		}
+		log.Printf("Emissions RPC version %s is no longer supported, detecting again", version)
+	}
+
+	for _, v := range c.versions {
+		value, err := c.abciQuery(ctx, req, fmt.Sprintf("/emissions.%s.QueryService/%s", v, method), data)
+		if errors.Is(err, errUnknownQueryPath) {
+			continue
+		}
+		if err == nil {
+			c.mu.Lock()
+			c.version = v
+			c.mu.Unlock()
+			log.Printf("Using emissions RPC version %s", v)
+		}
+		return value, err
+	}
+
+	return nil, fmt.Errorf("none of the emissions versions %v are served over RPC: %w", c.versions, errUnknownQueryPath)
+}
+
+// abciQuery performs an abci_query with retries and returns the raw response value
+func (c *RPCClient) abciQuery(ctx context.Context, req request, path string, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.retry.Deadline)
+	defer cancel()
+
+	var (
+		value []byte
+		err   error
+	)
+	for attempt := 1; attempt <= c.retry.MaxAttempts; attempt++ {
+		value, err = c.doABCIQuery(ctx, req, path, data)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Queried %s over RPC after %d retries", req.endpoint, attempt-1)
+			}
+			return value, nil
+		}
+		if errors.Is(err, errUnknownQueryPath) || !isRetryable(err) || ctx.Err() != nil || attempt == c.retry.MaxAttempts {
+			break
+		}
+
+		delay := c.retry.retryDelay(attempt-1, err)
+		log.Printf("Retrying %s over RPC in %s (attempt %d/%d): %v", req.endpoint, delay.Round(time.Millisecond), attempt+1, c.retry.MaxAttempts, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s aborted after %d attempts (%v): %w", req.endpoint, attempt, ctx.Err(), err)
+		}
+	}
+
+	return nil, err
+}
+
+// doABCIQuery performs a single abci_query JSON-RPC call
+func (c *RPCClient) doABCIQuery(ctx context.Context, req request, path string, data []byte) ([]byte, error) {
+	params := map[string]interface{}{
+		"path":  path,
+		"data":  fmt.Sprintf("%x", data),
+		"prove": false,
+	}
+	if height := HeightFrom(ctx); height > 0 {
+		params["height"] = strconv.FormatInt(height, 10)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "abci_query",
+		"params":  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s query: %w", req.endpoint, err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", req.endpoint, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", req.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, statusError(resp, req)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response body: %w", req.endpoint, err)
+	}
+
+	var result abciQueryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &APIError{Kind: ErrSchemaMismatch, Endpoint: req.endpoint, TopicID: req.topicID, Address: req.address, Err: err}
+	}
+	if result.Error != nil {
+		return nil, &APIError{
+			Kind:     ErrServer,
+			Endpoint: req.endpoint,
+			TopicID:  req.topicID,
+			Address:  req.address,
+			Err:      fmt.Errorf("rpc error %d: %s %s", result.Error.Code, result.Error.Message, result.Error.Data),
+		}
+	}
+
+	response := result.Result.Response
+	if response.Code != 0 {
+		// Code 6 of the sdk codespace is an unknown request path. Modules number their
+		// own errors from 1 in their codespace, so the code alone is ambiguous.
+		if response.Codespace == "sdk" && response.Code == 6 {
+			return nil, fmt.Errorf("%s: %w: %s", path, errUnknownQueryPath, response.Log)
+		}
+		return nil, &APIError{
+			Kind:     ErrRequestFailed,
+			Endpoint: req.endpoint,
+			TopicID:  req.topicID,
+			Address:  req.address,
+			Err:      fmt.Errorf("abci code %s/%d: %s", response.Codespace, response.Code, response.Log),
+		}
+	}
+
+	return response.Value, nil
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dntjd1097/allora-checker-bot/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// abciCall is an abci_query received by the stand-in server
+type abciCall struct {
+	Path   string
+	Data   []byte
+	Height string
+}
+
+// abciResult is the response the stand-in server returns for an abci_query
+type abciResult struct {
+	Code      uint32
+	Codespace string
+	Value     []byte
+}
+
+// newABCIServer starts a stand-in CometBFT RPC server answering abci_query with handle
+func newABCIServer(t *testing.T, handle func(call abciCall) abciResult) (*httptest.Server, func() []abciCall) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var calls []abciCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			Params struct {
+				Path   string `json:"path"`
+				Data   string `json:"data"`
+				Height string `json:"height"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "abci_query" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		data, err := hex.DecodeString(req.Params.Data)
+		if err != nil {
+			http.Error(w, "bad data", http.StatusBadRequest)
+			return
+		}
+
+		call := abciCall{Path: req.Params.Path, Data: data, Height: req.Params.Height}
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+
+		result := handle(call)
+		var resp abciQueryResponse
+		resp.Result.Response.Code = result.Code
+		resp.Result.Response.Codespace = result.Codespace
+		resp.Result.Response.Value = result.Value
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []abciCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]abciCall(nil), calls...)
+	}
+}
+
+// appendMessageField appends an embedded message field to a protobuf message
+func appendMessageField(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// workerValue encodes a WorkerAttributedValue or RegretInformedWeight
+func workerValue(worker, value string) []byte {
+	b := appendStringField(nil, fieldWorker, worker)
+	return appendStringField(b, fieldWorkerValue, value)
+}
+
+func TestRPCClientFetchScore(t *testing.T) {
+	score := appendUint64Field(nil, fieldScoreTopicID, 7)
+	score = appendUint64Field(score, fieldScoreBlockHeight, 123456)
+	score = appendStringField(score, fieldScoreAddress, "allo1worker")
+	score = appendStringField(score, fieldScoreValue, "0.25")
+	value := appendMessageField(nil, fieldScoreResponseScore, score)
+
+	srv, calls := newABCIServer(t, func(call abciCall) abciResult {
+		if call.Path != "/emissions.v9.QueryService/GetInfererScoreEma" {
+			return abciResult{Code: 6, Codespace: "sdk"}
+		}
+		return abciResult{Value: value}
+	})
+
+	c := NewRPCClient(srv.URL, RetryPolicy{MaxAttempts: 1}, nil)
+	got, err := c.FetchScore(WithHeight(context.Background(), 42), models.RoleInferer, "7", "allo1worker")
+	if err != nil {
+		t.Fatalf("FetchScore: %v", err)
+	}
+
+	want := models.ScoreData{TopicID: "7", BlockHeight: "123456", Address: "allo1worker", Score: "0.25"}
+	if *got != want {
+		t.Errorf("FetchScore = %+v, want %+v", *got, want)
+	}
+
+	received := calls()
+	if len(received) != 1 {
+		t.Fatalf("got %d queries, want 1", len(received))
+	}
+	wantData := appendUint64Field(nil, 1, 7)
+	wantData = appendStringField(wantData, 2, "allo1worker")
+	if string(received[0].Data) != string(wantData) {
+		t.Errorf("query data = %x, want %x", received[0].Data, wantData)
+	}
+	if received[0].Height != "42" {
+		t.Errorf("query height = %q, want 42", received[0].Height)
+	}
+}
+
+func TestRPCClientFetchNetworkInferences(t *testing.T) {
+	bundle := appendUint64Field(nil, fieldBundleTopicID, 3)
+	bundle = appendStringField(bundle, fieldBundleCombinedValue, "101.5")
+	bundle = appendMessageField(bundle, fieldBundleInfererValues, workerValue("allo1a", "100"))
+	bundle = appendMessageField(bundle, fieldBundleInfererValues, workerValue("allo1b", "103"))
+	bundle = appendStringField(bundle, fieldBundleNaiveValue, "99.9")
+
+	value := appendMessageField(nil, fieldNetworkInferences, bundle)
+	value = appendMessageField(value, fieldInfererWeights, workerValue("allo1a", "0.7"))
+	value = appendMessageField(value, fieldInfererWeights, workerValue("allo1b", "0.3"))
+	// Fields the client does not read are skipped
+	value = appendUint64Field(value, 9, 1)
+
+	srv, _ := newABCIServer(t, func(call abciCall) abciResult {
+		if call.Path != "/emissions.v9.QueryService/GetLatestNetworkInferences" {
+			return abciResult{Code: 6, Codespace: "sdk"}
+		}
+		return abciResult{Value: value}
+	})
+
+	c := NewRPCClient(srv.URL, RetryPolicy{MaxAttempts: 1}, nil)
+	got, err := c.FetchNetworkInferences(context.Background(), "3")
+	if err != nil {
+		t.Fatalf("FetchNetworkInferences: %v", err)
+	}
+
+	inferences := got.NetworkInferences
+	if inferences.TopicID != "3" || inferences.CombinedValue != "101.5" || inferences.NaiveValue != "99.9" {
+		t.Errorf("value bundle = %+v", inferences)
+	}
+	wantValues := []models.InfererValue{{Worker: "allo1a", Value: "100"}, {Worker: "allo1b", Value: "103"}}
+	if len(inferences.InfererValues) != len(wantValues) {
+		t.Fatalf("inferer values = %+v, want %+v", inferences.InfererValues, wantValues)
+	}
+	for i, v := range wantValues {
+		if inferences.InfererValues[i] != v {
+			t.Errorf("inferer value %d = %+v, want %+v", i, inferences.InfererValues[i], v)
+		}
+	}
+	wantWeights := []models.InfererWeight{{Worker: "allo1a", Weight: "0.7"}, {Worker: "allo1b", Weight: "0.3"}}
+	if len(got.InfererWeights) != len(wantWeights) {
+		t.Fatalf("inferer weights = %+v, want %+v", got.InfererWeights, wantWeights)
+	}
+	for i, w := range wantWeights {
+		if got.InfererWeights[i] != w {
+			t.Errorf("inferer weight %d = %+v, want %+v", i, got.InfererWeights[i], w)
+		}
+	}
+}
+
+func TestRPCClientDetectsVersion(t *testing.T) {
+	score := appendStringField(nil, fieldScoreValue, "1")
+	srv, calls := newABCIServer(t, func(call abciCall) abciResult {
+		if call.Path != "/emissions.v8.QueryService/GetCurrentLowestInfererScore" {
+			return abciResult{Code: 6, Codespace: "sdk"}
+		}
+		return abciResult{Value: appendMessageField(nil, fieldScoreResponseScore, score)}
+	})
+
+	c := NewRPCClient(srv.URL, RetryPolicy{MaxAttempts: 1}, nil)
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchLowestScore(context.Background(), models.RoleInferer, "1"); err != nil {
+			t.Fatalf("FetchLowestScore: %v", err)
+		}
+	}
+
+	// v9 is probed once, then v8 is used directly
+	if n := len(calls()); n != 3 {
+		t.Errorf("got %d queries, want 3", n)
+	}
+}
+
+func TestRPCClientModuleErrorIsNotUnknownPath(t *testing.T) {
+	srv, calls := newABCIServer(t, func(call abciCall) abciResult {
+		return abciResult{Code: 6, Codespace: "emissions"}
+	})
+
+	c := NewRPCClient(srv.URL, RetryPolicy{MaxAttempts: 1}, nil)
+	_, err := c.FetchScore(context.Background(), models.RoleInferer, "1", "allo1worker")
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Fatalf("FetchScore error = %v, want %v", err, ErrRequestFailed)
+	}
+	if n := len(calls()); n != 1 {
+		t.Errorf("got %d queries, want 1", n)
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultEmissionsVersions lists the emissions query versions tried when none are configured
+var DefaultEmissionsVersions = []string{"v9", "v8", "v7"}
+
+// VersionDetector is implemented by clients that negotiate the emissions API version
+type VersionDetector interface {
+	DetectVersion(ctx context.Context) (string, error)
+	EmissionsVersion() string
+}
+
+var _ VersionDetector = (*AlloraClient)(nil)
+
+// versionRetryInterval is how long a failed version detection is reused before probing again
+const versionRetryInterval = time.Minute
+
+// versionProbe is an in-flight version detection that concurrent callers wait on
+type versionProbe struct {
+	done    chan struct{}
+	version string
+	err     error
+}
+
+// DetectVersion probes the configured emissions versions in order and caches the first supported one.
+// Concurrent callers share a single probe.
+func (c *AlloraClient) DetectVersion(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if probe := c.probe; probe != nil {
+		c.mu.Unlock()
+		select {
+		case <-probe.done:
+			return probe.version, probe.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	probe := &versionProbe{done: make(chan struct{})}
+	c.probe = probe
+	c.mu.Unlock()
+
+	probe.version, probe.err = c.probeVersions(ctx)
+	close(probe.done)
+
+	c.mu.Lock()
+	c.probe = nil
+	// A cancelled probe says nothing about the API, so it does not delay the next one
+	if probe.err != nil && ctx.Err() == nil {
+		c.probeFailed = time.Now()
+	}
+	c.mu.Unlock()
+
+	return probe.version, probe.err
+}
+
+// probeVersions requests the params of each configured version until one is served
+func (c *AlloraClient) probeVersions(ctx context.Context) (string, error) {
+	for _, v := range c.versions {
+		req := request{
+			endpoint: "params",
+			url:      fmt.Sprintf("%s/emissions/%s/params", c.apiURL, v),
+		}
+
+		var params json.RawMessage
+		err := c.getJSON(ctx, req, &params)
+		if err == nil {
+			c.setVersion(v)
+			log.Printf("Using emissions API version %s", v)
+			return v, nil
+		}
+
+		// Only an unsupported route means the version should be skipped
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || (apiErr.Kind != ErrNotFound && apiErr.Kind != ErrUnexpectedStatus) {
+			return "", fmt.Errorf("failed to detect emissions version: %w", err)
+		}
+		log.Printf("Emissions API version %s is not supported: %v", v, err)
+	}
+
+	return "", fmt.Errorf("none of the emissions versions %v are supported", c.versions)
+}
+
+// EmissionsVersion returns the detected emissions version, or an empty string if not detected yet
+func (c *AlloraClient) EmissionsVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// emissionsVersion returns the cached version, detecting it on first use.
+// If detection fails the first configured version is used without caching it,
+// and detection is not retried for versionRetryInterval.
+func (c *AlloraClient) emissionsVersion(ctx context.Context) string {
+	c.mu.Lock()
+	v, failed := c.version, c.probeFailed
+	c.mu.Unlock()
+	if v != "" {
+		return v
+	}
+	if time.Since(failed) < versionRetryInterval {
+		return c.versions[0]
+	}
+
+	v, err := c.DetectVersion(ctx)
+	if err != nil {
+		log.Printf("Falling back to emissions version %s: %v", c.versions[0], err)
+		return c.versions[0]
+	}
+	return v
+}
+
+// setVersion caches the emissions version in use
+func (c *AlloraClient) setVersion(v string) {
+	c.mu.Lock()
+	c.version = v
+	c.mu.Unlock()
+}
+
+// checkVersion clears the cached version when an emissions route is no longer implemented,
+// which happens after a chain upgrade removes the version in use
+func (c *AlloraClient) checkVersion(err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == 501 {
+		log.Printf("Emissions version %s is no longer supported, detecting again", c.EmissionsVersion())
+		c.setVersion("")
+	}
+}